@@ -0,0 +1,348 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// StatsReader provides read-only access to server info
+type StatsReader interface {
+	// GetStats fetches info about Icecast server
+	GetStats() (*Stats, error)
+
+	// ListMounts fetches info about mounted sources
+	ListMounts() ([]*Mount, error)
+
+	// ListClients fetches list of listeners connected to given mount point
+	ListClients(mount string) ([]*Listener, error)
+}
+
+// MetadataWriter provides access to source metadata
+type MetadataWriter interface {
+	// UpdateMeta updates meta for given mount source
+	UpdateMeta(mount string, meta TrackMeta) error
+}
+
+// ListenerManager provides access to listeners management
+type ListenerManager interface {
+	// MoveClients moves clients from one source to another
+	MoveClients(mount, dest string) error
+
+	// KillClient kills client with given ID connected to given mount point
+	KillClient(mount string, id int) error
+}
+
+// SourceManager provides access to sources management
+type SourceManager interface {
+	// UpdateFallback updates fallback for given mount source
+	UpdateFallback(mount, fallback string) error
+
+	// KillSource kills the source with given mount point
+	KillSource(mount string) error
+}
+
+// Client provides full access to Icecast admin API
+type Client interface {
+	StatsReader
+	MetadataWriter
+	ListenerManager
+	SourceManager
+}
+
+// Server is backend-neutral streaming server API implemented by Icecast and
+// SHOUTcast DNAS clients
+type Server interface {
+	StatsReader
+	MetadataWriter
+
+	// KillClient kills client with given ID connected to given mount point
+	KillClient(mount string, id int) error
+
+	// KillSource kills the source with given mount point
+	KillSource(mount string) error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReadOnlyClient is client which rejects all modifying operations
+type ReadOnlyClient struct {
+	client Client
+}
+
+// ScopedClient is client which allows operations only on mounts matching
+// given glob patterns
+type ScopedClient struct {
+	client   Client
+	patterns []string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrReadOnly        = errors.New("Operation is not allowed for read-only client")
+	ErrMountNotAllowed = errors.New("Operation is not allowed for mount")
+	ErrNoPatterns      = errors.New("No mount patterns defined")
+	ErrNilClient       = errors.New("Client is nil")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	_ Client = (*API)(nil)
+	_ Client = (*ReadOnlyClient)(nil)
+	_ Client = (*ScopedClient)(nil)
+	_ Server = (*API)(nil)
+	_ Server = (*SHOUTcastAPI)(nil)
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewReadOnlyClient creates new read-only wrapper for given client
+func NewReadOnlyClient(client Client) (*ReadOnlyClient, error) {
+	if client == nil {
+		return nil, ErrNilClient
+	}
+
+	return &ReadOnlyClient{client: client}, nil
+}
+
+// NewScopedClient creates new wrapper for given client which allows operations
+// only on mounts matching given glob patterns (e.g. "/live*")
+func NewScopedClient(client Client, patterns ...string) (*ScopedClient, error) {
+	if client == nil {
+		return nil, ErrNilClient
+	}
+
+	if len(patterns) == 0 {
+		return nil, ErrNoPatterns
+	}
+
+	c := &ScopedClient{client: client}
+
+	for _, p := range patterns {
+		p = normalizeMount(p)
+
+		_, err := path.Match(p, "")
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid mount pattern %q: %w", p, err)
+		}
+
+		c.patterns = append(c.patterns, p)
+	}
+
+	return c, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetStats fetches info about Icecast server
+func (c *ReadOnlyClient) GetStats() (*Stats, error) {
+	return c.client.GetStats()
+}
+
+// ListMounts fetches info about mounted sources
+func (c *ReadOnlyClient) ListMounts() ([]*Mount, error) {
+	return c.client.ListMounts()
+}
+
+// ListClients fetches list of listeners connected to given mount point
+func (c *ReadOnlyClient) ListClients(mount string) ([]*Listener, error) {
+	return c.client.ListClients(mount)
+}
+
+// UpdateMeta always returns ErrReadOnly
+func (c *ReadOnlyClient) UpdateMeta(mount string, meta TrackMeta) error {
+	return ErrReadOnly
+}
+
+// MoveClients always returns ErrReadOnly
+func (c *ReadOnlyClient) MoveClients(mount, dest string) error {
+	return ErrReadOnly
+}
+
+// KillClient always returns ErrReadOnly
+func (c *ReadOnlyClient) KillClient(mount string, id int) error {
+	return ErrReadOnly
+}
+
+// UpdateFallback always returns ErrReadOnly
+func (c *ReadOnlyClient) UpdateFallback(mount, fallback string) error {
+	return ErrReadOnly
+}
+
+// KillSource always returns ErrReadOnly
+func (c *ReadOnlyClient) KillSource(mount string) error {
+	return ErrReadOnly
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsAllowed returns true if operations on given mount are allowed
+func (c *ScopedClient) IsAllowed(mount string) bool {
+	mount = normalizeMount(mount)
+
+	for _, p := range c.patterns {
+		if ok, _ := path.Match(p, mount); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetStats fetches info about Icecast server. Result contains only allowed
+// sources and their warnings, server-wide stats and server info are removed.
+func (c *ScopedClient) GetStats() (*Stats, error) {
+	stats, err := c.client.GetStats()
+
+	if err != nil || stats == nil {
+		return stats, err
+	}
+
+	result := *stats
+	result.Info, result.Stats, result.Sources = nil, nil, nil
+	result.Warnings = c.filterWarnings(stats)
+
+	for mount, source := range stats.Sources {
+		if !c.IsAllowed(mount) {
+			continue
+		}
+
+		if result.Sources == nil {
+			result.Sources = make(Sources)
+		}
+
+		result.Sources[mount] = source
+	}
+
+	return &result, nil
+}
+
+// ListMounts fetches info about allowed mounted sources
+func (c *ScopedClient) ListMounts() ([]*Mount, error) {
+	mounts, err := c.client.ListMounts()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Mount
+
+	for _, m := range mounts {
+		if c.IsAllowed(m.Path) {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// ListClients fetches list of listeners connected to given mount point
+func (c *ScopedClient) ListClients(mount string) ([]*Listener, error) {
+	err := c.checkMounts(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.client.ListClients(mount)
+}
+
+// UpdateMeta updates meta for given mount source
+func (c *ScopedClient) UpdateMeta(mount string, meta TrackMeta) error {
+	err := c.checkMounts(mount)
+
+	if err != nil {
+		return err
+	}
+
+	return c.client.UpdateMeta(mount, meta)
+}
+
+// MoveClients moves clients from one source to another. Both mounts must be
+// allowed.
+func (c *ScopedClient) MoveClients(mount, dest string) error {
+	err := c.checkMounts(mount, dest)
+
+	if err != nil {
+		return err
+	}
+
+	return c.client.MoveClients(mount, dest)
+}
+
+// KillClient kills client with given ID connected to given mount point
+func (c *ScopedClient) KillClient(mount string, id int) error {
+	err := c.checkMounts(mount)
+
+	if err != nil {
+		return err
+	}
+
+	return c.client.KillClient(mount, id)
+}
+
+// UpdateFallback updates fallback for given mount source. Both mounts must be
+// allowed.
+func (c *ScopedClient) UpdateFallback(mount, fallback string) error {
+	err := c.checkMounts(mount, fallback)
+
+	if err != nil {
+		return err
+	}
+
+	return c.client.UpdateFallback(mount, fallback)
+}
+
+// KillSource kills the source with given mount point
+func (c *ScopedClient) KillSource(mount string) error {
+	err := c.checkMounts(mount)
+
+	if err != nil {
+		return err
+	}
+
+	return c.client.KillSource(mount)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// filterWarnings returns server-level warnings and warnings for allowed sources
+func (c *ScopedClient) filterWarnings(stats *Stats) []string {
+	var result []string
+
+	for _, warning := range stats.Warnings {
+		mount, _, found := strings.Cut(warning, ": ")
+
+		if found && stats.Sources[mount] != nil && !c.IsAllowed(mount) {
+			continue
+		}
+
+		result = append(result, warning)
+	}
+
+	return result
+}
+
+// checkMounts returns error if any of given mounts is not allowed
+func (c *ScopedClient) checkMounts(mounts ...string) error {
+	for _, m := range mounts {
+		if !c.IsAllowed(m) {
+			return fmt.Errorf("%w %s", ErrMountNotAllowed, normalizeMount(m))
+		}
+	}
+
+	return nil
+}
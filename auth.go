@@ -0,0 +1,218 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	AUTH_LISTENER_ADD    = "listener_add"
+	AUTH_LISTENER_REMOVE = "listener_remove"
+	AUTH_MOUNT_ADD       = "mount_add"
+	AUTH_MOUNT_REMOVE    = "mount_remove"
+	AUTH_STREAM_AUTH     = "stream_auth"
+)
+
+const (
+	// AUTH_HEADER is default header used by Icecast to check if client is allowed
+	AUTH_HEADER = "icecast-auth-user"
+
+	// AUTH_HEADER_VALUE is default value of auth header for allowed clients
+	AUTH_HEADER_VALUE = "1"
+
+	// AUTH_TIMELIMIT_HEADER is default header with listening time limit in seconds
+	AUTH_TIMELIMIT_HEADER = "icecast-auth-timelimit"
+
+	// AUTH_MESSAGE_HEADER is default header with rejection reason
+	AUTH_MESSAGE_HEADER = "icecast-auth-message"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AuthRequest contains data from Icecast URL authentication callback
+type AuthRequest struct {
+	Action    string
+	Server    string
+	Port      int
+	Mount     string
+	Query     url.Values // Query from listener request URL
+	ClientID  int
+	User      string
+	Password  string
+	IP        string
+	UserAgent string
+	Referer   string
+	Duration  time.Duration // Listening duration (only for listener_remove)
+	Admin     bool          // Request is admin request (only for stream_auth)
+}
+
+// AuthResult contains authentication decision
+type AuthResult struct {
+	Allow     bool
+	Message   string        // Rejection reason
+	TimeLimit time.Duration // Max listening time (0 - unlimited)
+}
+
+// Authenticator decides if client is allowed to connect
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *AuthRequest) (AuthResult, error)
+}
+
+// AuthenticatorFunc is adapter which allows usage of ordinary functions as
+// authenticators
+type AuthenticatorFunc func(ctx context.Context, r *AuthRequest) (AuthResult, error)
+
+// AuthHandler is HTTP handler for Icecast URL authentication callbacks
+type AuthHandler struct {
+	Authenticator Authenticator
+
+	Header          string // Header used for allowed clients (auth_header option)
+	HeaderValue     string // Value of header for allowed clients
+	TimeLimitHeader string // Header with time limit (timelimit_header option)
+	MessageHeader   string // Header with rejection reason
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewAuthHandler creates new handler for URL authentication callbacks
+func NewAuthHandler(auth Authenticator) *AuthHandler {
+	return &AuthHandler{
+		Authenticator:   auth,
+		Header:          AUTH_HEADER,
+		HeaderValue:     AUTH_HEADER_VALUE,
+		TimeLimitHeader: AUTH_TIMELIMIT_HEADER,
+		MessageHeader:   AUTH_MESSAGE_HEADER,
+	}
+}
+
+// ParseAuthRequest parses URL authentication callback request
+func ParseAuthRequest(r *http.Request) (*AuthRequest, error) {
+	err := r.ParseForm()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse callback data: %w", err)
+	}
+
+	form := r.Form
+	action := form.Get("action")
+
+	switch action {
+	case AUTH_LISTENER_ADD, AUTH_LISTENER_REMOVE, AUTH_MOUNT_ADD,
+		AUTH_MOUNT_REMOVE, AUTH_STREAM_AUTH:
+		// ok
+	case "":
+		return nil, fmt.Errorf("Callback action is empty")
+	default:
+		return nil, fmt.Errorf("Unknown callback action %q", action)
+	}
+
+	result := &AuthRequest{
+		Action:    action,
+		Server:    form.Get("server"),
+		User:      form.Get("user"),
+		Password:  form.Get("pass"),
+		IP:        form.Get("ip"),
+		UserAgent: form.Get("agent"),
+		Referer:   form.Get("referer"),
+		Admin:     form.Get("admin") == "1",
+	}
+
+	result.Port, _ = strconv.Atoi(form.Get("port"))
+	result.ClientID, _ = strconv.Atoi(form.Get("client"))
+
+	duration, _ := strconv.Atoi(form.Get("duration"))
+	result.Duration = time.Duration(duration) * time.Second
+
+	mount, query, _ := strings.Cut(form.Get("mount"), "?")
+
+	result.Mount = mount
+	result.Query, _ = url.ParseQuery(query)
+
+	if result.Mount == "" {
+		return nil, fmt.Errorf("Callback mount is empty")
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Authenticate calls f(ctx, r)
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, r *AuthRequest) (AuthResult, error) {
+	return f(ctx, r)
+}
+
+// ServeHTTP handles URL authentication callback
+func (h *AuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ar, err := ParseAuthRequest(r)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.Authenticator == nil {
+		http.Error(w, "Authenticator is not set", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.Authenticator.Authenticate(r.Context(), ar)
+
+	if err != nil {
+		// Icecast rejects client if response has no auth header
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if !result.Allow {
+		if result.Message != "" && h.MessageHeader != "" {
+			w.Header().Set(h.MessageHeader, result.Message)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set(
+		getOrDefault(h.Header, AUTH_HEADER),
+		getOrDefault(h.HeaderValue, AUTH_HEADER_VALUE),
+	)
+
+	if result.TimeLimit > 0 && h.TimeLimitHeader != "" {
+		// Limit is rounded up, because zero limit means unlimited listening
+		w.Header().Set(h.TimeLimitHeader, strconv.Itoa(int(math.Ceil(result.TimeLimit.Seconds()))))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getOrDefault returns given value or default value if value is empty
+func getOrDefault(value, defValue string) string {
+	if value == "" {
+		return defValue
+	}
+
+	return value
+}
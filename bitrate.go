@@ -0,0 +1,176 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	BITRATE_UNKNOWN  BitrateKind = iota // Bitrate is unknown
+	BITRATE_CONSTANT                    // Bitrate has exact value in bits per second
+	BITRATE_QUALITY                     // Bitrate is VBR quality level
+)
+
+// BITRATE_MAX_KBPS is max plain bitrate value which is treated as kbps. Larger
+// plain values are treated as bits per second ("128000").
+const BITRATE_MAX_KBPS = 10000
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// BitrateKind is kind of bitrate value
+type BitrateKind uint8
+
+// Bitrate contains info about stream bitrate
+type Bitrate struct {
+	Kind    BitrateKind
+	Value   int     // Bitrate in bits per second (nominal for quality levels)
+	Quality float64 // VBR quality level
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// vorbisNominal contains nominal bitrates (kbps) for Vorbis quality levels
+// from -1 to 10 (44.1 kHz stereo)
+var vorbisNominal = []float64{45, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 500}
+
+// opusNominal contains approximate bitrates (kbps) for quality levels from -1
+// to 10 used by encoders which configure Opus with Vorbis-like quality scale
+var opusNominal = []float64{24, 32, 48, 64, 80, 96, 112, 128, 160, 192, 256, 320}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewBitrate creates constant bitrate with given value in bits per second
+func NewBitrate(bps int) Bitrate {
+	if bps <= 0 {
+		return Bitrate{}
+	}
+
+	return Bitrate{Kind: BITRATE_CONSTANT, Value: bps}
+}
+
+// NewQualityBitrate creates VBR bitrate with given quality level. Nominal
+// bitrate is calculated for Vorbis and Opus codecs.
+func NewQualityBitrate(quality float64, codec Codec) Bitrate {
+	return Bitrate{
+		Kind:    BITRATE_QUALITY,
+		Quality: quality,
+		Value:   nominalBitrate(quality, codec),
+	}
+}
+
+// ParseBitrate parses bitrate value reported by Icecast ("128", "128kbps",
+// "Quality 0", "q5"). Plain numbers are treated as kbps unless they are greater
+// than BITRATE_MAX_KBPS.
+func ParseBitrate(data string, codec Codec) Bitrate {
+	data = strings.ToLower(strings.TrimSpace(data))
+
+	if data == "" {
+		return Bitrate{}
+	}
+
+	for _, prefix := range []string{"quality", "q"} {
+		if !strings.HasPrefix(data, prefix) {
+			continue
+		}
+
+		quality, err := strconv.ParseFloat(strings.TrimSpace(data[len(prefix):]), 64)
+
+		if err != nil {
+			return Bitrate{}
+		}
+
+		return NewQualityBitrate(quality, codec)
+	}
+
+	multiplier, isPlain := 1000.0, false
+
+	switch {
+	case strings.HasSuffix(data, "kbps"), strings.HasSuffix(data, "kbit/s"),
+		strings.HasSuffix(data, "k"):
+		data = strings.TrimRight(data, "kbpsit/")
+	case strings.HasSuffix(data, "bps"):
+		data, multiplier = strings.TrimSuffix(data, "bps"), 1
+	default:
+		isPlain = true
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(data), 64)
+
+	if err != nil {
+		return Bitrate{}
+	}
+
+	if isPlain && value > BITRATE_MAX_KBPS {
+		multiplier = 1
+	}
+
+	return NewBitrate(int(math.Round(value * multiplier)))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// BPS returns bitrate in bits per second (nominal for quality levels)
+func (b Bitrate) BPS() int {
+	return b.Value
+}
+
+// Kbps returns bitrate in kilobits per second (nominal for quality levels)
+func (b Bitrate) Kbps() int {
+	return b.Value / 1000
+}
+
+// IsKnown returns true if bitrate is known
+func (b Bitrate) IsKnown() bool {
+	return b.Kind != BITRATE_UNKNOWN
+}
+
+// IsVBR returns true if bitrate is VBR quality level
+func (b Bitrate) IsVBR() bool {
+	return b.Kind == BITRATE_QUALITY
+}
+
+// String returns bitrate in Icecast format (kbps or quality level)
+func (b Bitrate) String() string {
+	switch b.Kind {
+	case BITRATE_CONSTANT:
+		return strconv.Itoa(b.Kbps())
+	case BITRATE_QUALITY:
+		return "Quality " + strconv.FormatFloat(b.Quality, 'f', -1, 64)
+	}
+
+	return ""
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// nominalBitrate returns nominal bitrate in bits per second for given quality
+// level
+func nominalBitrate(quality float64, codec Codec) int {
+	var table []float64
+
+	switch codec {
+	case CODEC_VORBIS:
+		table = vorbisNominal
+	case CODEC_OPUS:
+		table = opusNominal
+	default:
+		return 0
+	}
+
+	index := math.Max(-1, math.Min(10, quality)) + 1
+	low := math.Floor(index)
+	high := math.Min(low+1, float64(len(table)-1))
+	kbps := table[int(low)] + (table[int(high)]-table[int(low)])*(index-low)
+
+	return int(math.Round(kbps * 1000))
+}
@@ -0,0 +1,181 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// StatsCache is caching wrapper for fetching stats. Concurrent requests are
+// deduplicated, so only one request to Icecast is sent at a time.
+type StatsCache struct {
+	// TTL is period of time fetched stats are considered fresh
+	TTL time.Duration
+
+	// MaxStale is period of time after TTL expiration during which cached
+	// stats are returned if Icecast is unavailable (0 - always return cached
+	// stats on errors, negative value disables returning stale stats)
+	MaxStale time.Duration
+
+	api         *API
+	stats       *Stats
+	fetched     time.Time
+	invalidated bool
+	call        *statsCall
+	mu          sync.Mutex
+}
+
+// CachedStats contains stats with info about their freshness
+type CachedStats struct {
+	*Stats
+
+	// Fetched is date when stats were fetched
+	Fetched time.Time
+
+	// Stale is true if stats are outdated (refresh failed)
+	Stale bool
+
+	// Err is error of last refresh if stale stats were returned
+	Err error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// statsCall is in-flight stats request
+type statsCall struct {
+	done  chan struct{}
+	stats *Stats
+	err   error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewStatsCache creates new stats cache with given TTL
+func NewStatsCache(api *API, ttl time.Duration) (*StatsCache, error) {
+	if api == nil {
+		return nil, ErrNilAPI
+	}
+
+	return &StatsCache{api: api, TTL: ttl}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetStats returns cached stats or fetches new stats if cache is outdated
+func (c *StatsCache) GetStats() (*Stats, error) {
+	cached, err := c.Fetch()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cached.Stats, nil
+}
+
+// Fetch returns cached stats with info about their freshness. If cache is outdated
+// stats are fetched from Icecast. If fetching fails, stale stats are returned
+// along with error in Err field.
+func (c *StatsCache) Fetch() (*CachedStats, error) {
+	c.mu.Lock()
+
+	if c.stats != nil && !c.invalidated && time.Since(c.fetched) < c.TTL {
+		defer c.mu.Unlock()
+		return &CachedStats{Stats: c.stats, Fetched: c.fetched}, nil
+	}
+
+	call := c.call
+
+	if call == nil {
+		call = &statsCall{done: make(chan struct{})}
+		c.call = call
+		c.mu.Unlock()
+
+		c.refresh(call)
+	} else {
+		c.mu.Unlock()
+		<-call.done
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call.err == nil {
+		return &CachedStats{Stats: c.stats, Fetched: c.fetched}, nil
+	}
+
+	if c.stats != nil && c.isStaleAllowed() {
+		return &CachedStats{
+			Stats:   c.stats,
+			Fetched: c.fetched,
+			Stale:   true,
+			Err:     call.err,
+		}, nil
+	}
+
+	return nil, call.err
+}
+
+// Age returns age of cached stats (-1 if there is no cached data)
+func (c *StatsCache) Age() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stats == nil {
+		return -1
+	}
+
+	return time.Since(c.fetched)
+}
+
+// Invalidate marks cached stats as outdated, so they will be fetched on next
+// request. Invalidated stats still can be returned if Icecast is unavailable.
+func (c *StatsCache) Invalidate() {
+	c.mu.Lock()
+	c.invalidated = true
+	c.mu.Unlock()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Age returns age of stats
+func (s *CachedStats) Age() time.Duration {
+	return time.Since(s.Fetched)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// refresh fetches stats from Icecast
+func (c *StatsCache) refresh(call *statsCall) {
+	call.stats, call.err = c.api.GetStats()
+
+	c.mu.Lock()
+
+	if call.err == nil {
+		c.stats, c.fetched, c.invalidated = call.stats, time.Now(), false
+	}
+
+	c.call = nil
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+// isStaleAllowed returns true if cached stats can be returned on error
+func (c *StatsCache) isStaleAllowed() bool {
+	switch {
+	case c.MaxStale < 0:
+		return false
+	case c.MaxStale == 0:
+		return true
+	}
+
+	return time.Since(c.fetched) < c.TTL+c.MaxStale
+}
@@ -0,0 +1,132 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DEFAULT_CLIENTS_WORKERS is default number of concurrent requests used for
+// fetching listeners
+const DEFAULT_CLIENTS_WORKERS = 4
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MountListener is listener connected to mount
+type MountListener struct {
+	*Listener
+
+	Mount string
+}
+
+// MountListeners is slice with listeners from all mounts
+type MountListeners []*MountListener
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ListAllClients fetches listeners of all mounts using given number of concurrent
+// requests (0 - use default number). If some mounts can't be processed, listeners
+// from other mounts are returned along with error.
+func (api *API) ListAllClients(workers int) (MountListeners, error) {
+	mounts, err := api.ListMounts()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 {
+		workers = DEFAULT_CLIENTS_WORKERS
+	}
+
+	var result MountListeners
+	var errs []error
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	queue := make(chan string)
+
+	for range min(workers, len(mounts)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for mount := range queue {
+				listeners, err := api.ListClients(mount)
+
+				mu.Lock()
+
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", mount, err))
+				}
+
+				for _, l := range listeners {
+					result = append(result, &MountListener{Listener: l, Mount: mount})
+				}
+
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, m := range mounts {
+		queue <- m.Path
+	}
+
+	close(queue)
+	wg.Wait()
+
+	slices.SortFunc(result, func(a, b *MountListener) int {
+		return cmp.Or(cmp.Compare(a.Mount, b.Mount), cmp.Compare(a.ID, b.ID))
+	})
+
+	return result, errors.Join(errs...)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// FindByIP returns all listeners with given IP
+func (l MountListeners) FindByIP(ip string) MountListeners {
+	var result MountListeners
+
+	for _, ml := range l {
+		if ml.IP == ip {
+			result = append(result, ml)
+		}
+	}
+
+	return result
+}
+
+// FindByID returns listener with given ID
+func (l MountListeners) FindByID(id int) *MountListener {
+	for _, ml := range l {
+		if ml.ID == id {
+			return ml
+		}
+	}
+
+	return nil
+}
+
+// Mounts returns listeners grouped by mount
+func (l MountListeners) Mounts() map[string]MountListeners {
+	result := make(map[string]MountListeners)
+
+	for _, ml := range l {
+		result[ml.Mount] = append(result[ml.Mount], ml)
+	}
+
+	return result
+}
@@ -0,0 +1,470 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Cluster is group of Icecast servers serving the same mounts
+type Cluster struct {
+	nodes []*ClusterNode
+}
+
+// ClusterNode is Icecast server in cluster
+type ClusterNode struct {
+	// Name is unique node name
+	Name string
+
+	// API is client for node admin API
+	API *API
+
+	// PublicURL is base URL used for redirecting listeners to node
+	PublicURL string
+
+	// MaxBitrate is max outgoing bitrate of node in bits per second
+	// (0 - unlimited)
+	MaxBitrate int
+}
+
+// Balancer is HTTP handler which redirects listeners to cluster node with the
+// most free capacity
+type Balancer struct {
+	// CacheTTL is period of time node stats are cached
+	CacheTTL time.Duration
+
+	// FetchTimeout is max period of time to wait for node stats (0 - unlimited)
+	FetchTimeout time.Duration
+
+	// StickyTTL is period of time listener is assigned to the same node
+	StickyTTL time.Duration
+
+	// TrustForwardedFor enables usage of X-Forwarded-For header for client IP
+	TrustForwardedFor bool
+
+	cluster   *Cluster
+	stats     map[string]*balancerStats
+	sticky    map[string]*balancerSticky
+	refresh   chan struct{}
+	lastClean time.Time
+	mu        sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// balancerStats contains cached node stats
+type balancerStats struct {
+	Stats   *Stats
+	Err     error
+	Fetched time.Time
+
+	// Assigned contains number of listeners redirected to node since stats
+	// were fetched
+	Assigned map[string]int
+
+	// AssignedBitrate is bitrate of listeners redirected to node since stats
+	// were fetched
+	AssignedBitrate int
+}
+
+// balancerScore contains node capacity for mount
+type balancerScore struct {
+	Free      int // Number of listeners node can accept (math.MaxInt - unlimited)
+	Listeners int // Number of listeners including assigned ones
+}
+
+// balancerFetch is result of fetching node stats
+type balancerFetch struct {
+	Node  string
+	Stats *balancerStats
+}
+
+// balancerSticky contains info about listener assignment
+type balancerSticky struct {
+	Node    string
+	Expires time.Time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrNoNodes          = errors.New("No cluster nodes defined")
+	ErrNoAvailableNodes = errors.New("No available nodes for mount")
+	ErrNodeTimeout      = errors.New("Node stats request timed out")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewCluster creates new cluster with given nodes
+func NewCluster(nodes ...*ClusterNode) (*Cluster, error) {
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	names := make(map[string]bool)
+
+	for _, n := range nodes {
+		switch {
+		case n == nil:
+			return nil, fmt.Errorf("Cluster node is nil")
+		case n.Name == "":
+			return nil, fmt.Errorf("Cluster node has no name")
+		case n.API == nil:
+			return nil, fmt.Errorf("Cluster node %q has no API client", n.Name)
+		case n.PublicURL == "":
+			return nil, fmt.Errorf("Cluster node %q has no public URL", n.Name)
+		case names[n.Name]:
+			return nil, fmt.Errorf("Cluster node %q is defined more than once", n.Name)
+		}
+
+		names[n.Name] = true
+	}
+
+	return &Cluster{nodes: nodes}, nil
+}
+
+// Nodes returns slice with all cluster nodes
+func (c *Cluster) Nodes() []*ClusterNode {
+	return append([]*ClusterNode(nil), c.nodes...)
+}
+
+// Node returns node with given name
+func (c *Cluster) Node(name string) *ClusterNode {
+	for _, n := range c.nodes {
+		if n.Name == name {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewBalancer creates new listener balancer for given cluster
+func NewBalancer(cluster *Cluster) *Balancer {
+	return &Balancer{
+		CacheTTL:     5 * time.Second,
+		FetchTimeout: 3 * time.Second,
+		StickyTTL:    10 * time.Minute,
+		cluster:      cluster,
+		stats:        make(map[string]*balancerStats),
+		sticky:       make(map[string]*balancerSticky),
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ServeHTTP redirects listener to the best node for requested mount
+func (b *Balancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	node, err := b.Pick(r.URL.Path, b.getClientIP(r))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	target := strings.TrimRight(node.PublicURL, "/") + r.URL.Path
+
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// Pick returns node with the most free capacity for given mount. Listeners
+// with the same IP are assigned to the same node while it has free capacity.
+func (b *Balancer) Pick(mount, ip string) (*ClusterNode, error) {
+	if b.cluster == nil {
+		return nil, ErrNoNodes
+	}
+
+	mount = normalizeMount(mount)
+
+	b.refreshStats()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	sticky := b.sticky[ip]
+
+	if ip != "" && sticky != nil && now.Before(sticky.Expires) {
+		node := b.cluster.Node(sticky.Node)
+
+		if node != nil {
+			_, ok := b.getScore(node, mount)
+
+			if ok {
+				sticky.Expires = now.Add(b.StickyTTL)
+				b.assign(node, mount)
+				return node, nil
+			}
+		}
+	}
+
+	var best *ClusterNode
+	var bestScore balancerScore
+
+	for _, node := range b.cluster.nodes {
+		score, ok := b.getScore(node, mount)
+
+		if ok && (best == nil || score.IsBetter(bestScore)) {
+			best, bestScore = node, score
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("%w %s", ErrNoAvailableNodes, mount)
+	}
+
+	b.assign(best, mount)
+
+	if ip != "" && b.StickyTTL > 0 {
+		b.sticky[ip] = &balancerSticky{Node: best.Name, Expires: now.Add(b.StickyTTL)}
+		b.cleanSticky(now)
+	}
+
+	return best, nil
+}
+
+// Invalidate removes cached stats, so they will be fetched on next request
+func (b *Balancer) Invalidate() {
+	b.mu.Lock()
+	b.stats = make(map[string]*balancerStats)
+	b.mu.Unlock()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// refreshStats fetches stats for nodes with outdated cache. Only one refresh
+// is running at a time, other callers wait for it.
+func (b *Balancer) refreshStats() {
+	b.mu.Lock()
+
+	if b.refresh != nil {
+		done := b.refresh
+		b.mu.Unlock()
+		<-done
+		return
+	}
+
+	var outdated []*ClusterNode
+
+	now := time.Now()
+
+	for _, node := range b.cluster.nodes {
+		cached := b.stats[node.Name]
+
+		if cached == nil || now.Sub(cached.Fetched) >= b.CacheTTL {
+			outdated = append(outdated, node)
+		}
+	}
+
+	if len(outdated) == 0 {
+		b.mu.Unlock()
+		return
+	}
+
+	done := make(chan struct{})
+	b.refresh = done
+	b.mu.Unlock()
+
+	fetched := b.fetchStats(outdated)
+
+	b.mu.Lock()
+
+	for _, f := range fetched {
+		b.stats[f.Node] = f.Stats
+	}
+
+	b.refresh = nil
+	b.mu.Unlock()
+
+	close(done)
+}
+
+// fetchStats fetches stats for given nodes. Nodes which don't respond within
+// FetchTimeout are marked as unavailable.
+func (b *Balancer) fetchStats(nodes []*ClusterNode) []*balancerFetch {
+	now := time.Now()
+	ch := make(chan *balancerFetch, len(nodes))
+
+	for _, node := range nodes {
+		go func(node *ClusterNode) {
+			stats, err := node.API.GetStats()
+
+			ch <- &balancerFetch{
+				Node: node.Name,
+				Stats: &balancerStats{
+					Stats:    stats,
+					Err:      err,
+					Fetched:  now,
+					Assigned: make(map[string]int),
+				},
+			}
+		}(node)
+	}
+
+	var timeout <-chan time.Time
+
+	if b.FetchTimeout > 0 {
+		timer := time.NewTimer(b.FetchTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var result []*balancerFetch
+
+	received := make(map[string]bool)
+
+LOOP:
+	for range nodes {
+		select {
+		case f := <-ch:
+			result = append(result, f)
+			received[f.Node] = true
+		case <-timeout:
+			break LOOP
+		}
+	}
+
+	for _, node := range nodes {
+		if !received[node.Name] {
+			result = append(result, &balancerFetch{
+				Node:  node.Name,
+				Stats: &balancerStats{Err: ErrNodeTimeout, Fetched: now},
+			})
+		}
+	}
+
+	return result
+}
+
+// getScore returns capacity of node for given mount and false if node can't
+// accept new listeners. Capacity is limited by both max listeners of mount
+// and max bitrate of node.
+func (b *Balancer) getScore(node *ClusterNode, mount string) (balancerScore, bool) {
+	cached := b.stats[node.Name]
+
+	if cached == nil || cached.Err != nil || cached.Stats == nil {
+		return balancerScore{}, false
+	}
+
+	source := cached.Stats.Sources[mount]
+
+	if source == nil || source.Stats == nil {
+		return balancerScore{}, false
+	}
+
+	score := balancerScore{
+		Free:      math.MaxInt,
+		Listeners: source.Stats.Listeners + cached.Assigned[mount],
+	}
+
+	if source.Stats.MaxListeners >= 0 {
+		score.Free = source.Stats.MaxListeners - score.Listeners
+	}
+
+	if node.MaxBitrate > 0 && cached.Stats.Stats != nil {
+		bitrate := cached.Stats.Stats.OutgoingBitrate + cached.AssignedBitrate
+		score.Free = min(score.Free, (node.MaxBitrate-bitrate)/getListenerBitrate(source))
+	}
+
+	return score, score.Free > 0
+}
+
+// assign counts listener redirected to node, so it is taken into account
+// until stats are fetched again
+func (b *Balancer) assign(node *ClusterNode, mount string) {
+	cached := b.stats[node.Name]
+
+	if cached == nil || cached.Stats == nil {
+		return
+	}
+
+	if cached.Assigned == nil {
+		cached.Assigned = make(map[string]int)
+	}
+
+	cached.Assigned[mount]++
+	cached.AssignedBitrate += getListenerBitrate(cached.Stats.Sources[mount])
+}
+
+// cleanSticky removes expired sticky assignments
+func (b *Balancer) cleanSticky(now time.Time) {
+	if now.Sub(b.lastClean) < time.Minute {
+		return
+	}
+
+	b.lastClean = now
+
+	for ip, s := range b.sticky {
+		if now.After(s.Expires) {
+			delete(b.sticky, ip)
+		}
+	}
+}
+
+// getClientIP returns client IP
+func (b *Balancer) getClientIP(r *http.Request) string {
+	if b.TrustForwardedFor {
+		ip, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ",")
+
+		if ip != "" {
+			return strings.TrimSpace(ip)
+		}
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsBetter returns true if node with this score can accept more listeners than
+// node with given score. Nodes with equal capacity are compared by number of
+// listeners.
+func (s balancerScore) IsBetter(score balancerScore) bool {
+	if s.Free != score.Free {
+		return s.Free > score.Free
+	}
+
+	return s.Listeners < score.Listeners
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getListenerBitrate returns bitrate of listener stream for given source
+func getListenerBitrate(source *Source) int {
+	if source != nil && source.AudioInfo != nil && source.AudioInfo.Bitrate.BPS() > 0 {
+		return source.AudioInfo.Bitrate.BPS()
+	}
+
+	return 128000
+}
@@ -0,0 +1,210 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	CODEC_UNKNOWN Codec = iota
+	CODEC_MP3
+	CODEC_AAC
+	CODEC_HEAAC
+	CODEC_VORBIS
+	CODEC_OPUS
+	CODEC_FLAC
+)
+
+// FLV sound format IDs used by Icecast-KH in audio_codecid
+const (
+	CODEC_ID_MP3    = 2
+	CODEC_ID_AAC    = 10
+	CODEC_ID_MP3_8K = 14
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Codec is audio codec
+type Codec uint8
+
+// AudioInfoValues contains key/value pairs from audio_info field
+// (ice-samplerate=48000;ice-bitrate=128;ice-channels=2)
+type AudioInfoValues map[string]string
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseAudioInfo parses raw audio info string
+func ParseAudioInfo(data string) AudioInfoValues {
+	result := AudioInfoValues{}
+
+	for _, pair := range strings.Split(data, ";") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if !ok || key == "" {
+			continue
+		}
+
+		result[key] = strings.TrimSpace(value)
+	}
+
+	return result
+}
+
+// CodecFromID returns codec for given FLV sound format ID
+func CodecFromID(id int) Codec {
+	switch id {
+	case CODEC_ID_MP3, CODEC_ID_MP3_8K:
+		return CODEC_MP3
+	case CODEC_ID_AAC:
+		return CODEC_AAC
+	}
+
+	return CODEC_UNKNOWN
+}
+
+// CodecFromType returns codec for given content type and subtype
+func CodecFromType(contentType, subType string) Codec {
+	subType = strings.ToLower(subType)
+
+	switch {
+	case strings.Contains(subType, "opus"):
+		return CODEC_OPUS
+	case strings.Contains(subType, "vorbis"):
+		return CODEC_VORBIS
+	case strings.Contains(subType, "flac"):
+		return CODEC_FLAC
+	}
+
+	contentType, _, _ = strings.Cut(strings.ToLower(contentType), ";")
+
+	switch strings.TrimSpace(contentType) {
+	case "audio/mpeg", "audio/mp3", "audio/x-mpeg", "audio/mpeg3":
+		return CODEC_MP3
+	case "audio/aac", "audio/x-aac", "audio/mp4":
+		return CODEC_AAC
+	case "audio/aacp":
+		return CODEC_HEAAC
+	case "audio/opus":
+		return CODEC_OPUS
+	case "audio/vorbis":
+		return CODEC_VORBIS
+	case "audio/flac", "audio/x-flac":
+		return CODEC_FLAC
+	}
+
+	return CODEC_UNKNOWN
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns codec name
+func (c Codec) String() string {
+	switch c {
+	case CODEC_MP3:
+		return "MP3"
+	case CODEC_AAC:
+		return "AAC"
+	case CODEC_HEAAC:
+		return "HE-AAC"
+	case CODEC_VORBIS:
+		return "Vorbis"
+	case CODEC_OPUS:
+		return "Opus"
+	case CODEC_FLAC:
+		return "FLAC"
+	}
+
+	return "Unknown"
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns value for given key. Key can be set with or without "ice-" prefix.
+func (v AudioInfoValues) Get(key string) string {
+	if v == nil {
+		return ""
+	}
+
+	key = strings.ToLower(key)
+	value, ok := v[key]
+
+	if ok {
+		return value
+	}
+
+	if strings.HasPrefix(key, "ice-") {
+		return v[strings.TrimPrefix(key, "ice-")]
+	}
+
+	return v["ice-"+key]
+}
+
+// GetInt returns value for given key as integer
+func (v AudioInfoValues) GetInt(key string) int {
+	value, _ := strconv.Atoi(v.Get(key))
+	return value
+}
+
+// Has returns true if values contain given key
+func (v AudioInfoValues) Has(key string) bool {
+	return v.Get(key) != ""
+}
+
+// SampleRate returns sample rate
+func (v AudioInfoValues) SampleRate() int {
+	return v.GetInt("samplerate")
+}
+
+// Channels returns number of channels
+func (v AudioInfoValues) Channels() int {
+	return v.GetInt("channels")
+}
+
+// Bitrate returns raw bitrate value (number in kbps or quality, like "Quality 0")
+func (v AudioInfoValues) Bitrate() string {
+	return v.Get("bitrate")
+}
+
+// Quality returns quality value
+func (v AudioInfoValues) Quality() string {
+	return v.Get("quality")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Values parses raw audio info
+func (i *AudioInfo) Values() AudioInfoValues {
+	if i == nil {
+		return AudioInfoValues{}
+	}
+
+	return ParseAudioInfo(i.RawInfo)
+}
+
+// Codec returns source codec. Codec is detected using codec ID or, if ID is
+// absent, using source content type and subtype.
+func (s *Source) Codec() Codec {
+	if s == nil {
+		return CODEC_UNKNOWN
+	}
+
+	if s.AudioInfo != nil && s.AudioInfo.Codec != CODEC_UNKNOWN {
+		return s.AudioInfo.Codec
+	}
+
+	if s.Info != nil {
+		return CodecFromType(s.Info.Type, s.Info.SubType)
+	}
+
+	return CODEC_UNKNOWN
+}
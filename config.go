@@ -0,0 +1,419 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	FLAG_UNSET Flag = 0 // Option is not set, Icecast default is used
+	FLAG_NO    Flag = 1 // Option is disabled (0)
+	FLAG_YES   Flag = 2 // Option is enabled (1)
+)
+
+const (
+	MOUNT_TYPE_NORMAL  = "normal"
+	MOUNT_TYPE_DEFAULT = "default"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Flag is boolean option from Icecast configuration
+type Flag uint8
+
+// Config contains Icecast server configuration (icecast.xml)
+type Config struct {
+	XMLName xml.Name `xml:"icecast"`
+
+	Location  string `xml:"location,omitempty"`
+	Admin     string `xml:"admin,omitempty"`
+	Hostname  string `xml:"hostname,omitempty"`
+	Fileserve Flag   `xml:"fileserve,omitempty"`
+
+	Limits         *ConfigLimits         `xml:"limits,omitempty"`
+	Authentication *ConfigAuthentication `xml:"authentication,omitempty"`
+	ListenSockets  []*ConfigListenSocket `xml:"listen-socket"`
+	Relays         []*ConfigRelay        `xml:"relay"`
+	Mounts         []*ConfigMount        `xml:"mount"`
+
+	// Extra contains configuration sections which have no typed
+	// representation (paths, logging, security, etc.)
+	Extra []*ConfigRaw `xml:",any"`
+}
+
+// ConfigLimits contains server limits
+type ConfigLimits struct {
+	Clients        int    `xml:"clients,omitempty"`
+	Sources        int    `xml:"sources,omitempty"`
+	Workers        int    `xml:"workers,omitempty"`
+	QueueSize      int    `xml:"queue-size,omitempty"`
+	ClientTimeout  int    `xml:"client-timeout,omitempty"`
+	HeaderTimeout  int    `xml:"header-timeout,omitempty"`
+	SourceTimeout  int    `xml:"source-timeout,omitempty"`
+	BurstOnConnect Flag   `xml:"burst-on-connect,omitempty"`
+	BurstSize      int    `xml:"burst-size,omitempty"`
+	MaxBandwidth   string `xml:"max-bandwidth,omitempty"`
+
+	// Extra contains limits which have no typed representation
+	Extra []*ConfigRaw `xml:",any"`
+}
+
+// ConfigAuthentication contains global credentials
+type ConfigAuthentication struct {
+	SourcePassword string `xml:"source-password,omitempty"`
+	RelayUser      string `xml:"relay-user,omitempty"`
+	RelayPassword  string `xml:"relay-password,omitempty"`
+	AdminUser      string `xml:"admin-user,omitempty"`
+	AdminPassword  string `xml:"admin-password,omitempty"`
+
+	// Extra contains authentication options which have no typed representation
+	// (e.g. roles in Icecast 2.5)
+	Extra []*ConfigRaw `xml:",any"`
+}
+
+// ConfigListenSocket contains listen socket configuration
+type ConfigListenSocket struct {
+	Port            int    `xml:"port"`
+	BindAddress     string `xml:"bind-address,omitempty"`
+	ShoutcastMount  string `xml:"shoutcast-mount,omitempty"`
+	ShoutcastCompat Flag   `xml:"shoutcast-compat,omitempty"`
+	SSL             Flag   `xml:"ssl,omitempty"`
+
+	// Extra contains listen socket options which have no typed representation
+	Extra []*ConfigRaw `xml:",any"`
+}
+
+// ConfigRelay contains relay configuration
+type ConfigRelay struct {
+	Server                 string `xml:"server"`
+	Port                   int    `xml:"port"`
+	Mount                  string `xml:"mount"`
+	LocalMount             string `xml:"local-mount,omitempty"`
+	Username               string `xml:"username,omitempty"`
+	Password               string `xml:"password,omitempty"`
+	RelayShoutcastMetadata Flag   `xml:"relay-shoutcast-metadata,omitempty"`
+	OnDemand               Flag   `xml:"on-demand,omitempty"`
+
+	// Extra contains relay options which have no typed representation
+	Extra []*ConfigRaw `xml:",any"`
+}
+
+// ConfigMount contains mount point configuration
+type ConfigMount struct {
+	Type string `xml:"type,attr,omitempty"`
+
+	MountName           string `xml:"mount-name,omitempty"`
+	Username            string `xml:"username,omitempty"`
+	Password            string `xml:"password,omitempty"`
+	MaxListeners        *int   `xml:"max-listeners,omitempty"`
+	MaxListenerDuration int    `xml:"max-listener-duration,omitempty"`
+	DumpFile            string `xml:"dump-file,omitempty"`
+	Intro               string `xml:"intro,omitempty"`
+	FallbackMount       string `xml:"fallback-mount,omitempty"`
+	FallbackOverride    Flag   `xml:"fallback-override,omitempty"`
+	FallbackWhenFull    Flag   `xml:"fallback-when-full,omitempty"`
+	Charset             string `xml:"charset,omitempty"`
+	Public              Flag   `xml:"public,omitempty"`
+	Hidden              Flag   `xml:"hidden,omitempty"`
+	StreamName          string `xml:"stream-name,omitempty"`
+	StreamDescription   string `xml:"stream-description,omitempty"`
+	StreamURL           string `xml:"stream-url,omitempty"`
+	Genre               string `xml:"genre,omitempty"`
+	Bitrate             string `xml:"bitrate,omitempty"`
+	ContentType         string `xml:"type,omitempty"`
+	Subtype             string `xml:"subtype,omitempty"`
+	BurstSize           int    `xml:"burst-size,omitempty"`
+	OnConnect           string `xml:"on-connect,omitempty"`
+	OnDisconnect        string `xml:"on-disconnect,omitempty"`
+
+	Authentication *ConfigMountAuthentication `xml:"authentication,omitempty"`
+
+	// Extra contains mount options which have no typed representation
+	Extra []*ConfigRaw `xml:",any"`
+}
+
+// ConfigMountAuthentication contains mount authentication configuration
+type ConfigMountAuthentication struct {
+	Type    string          `xml:"type,attr"`
+	Options []*ConfigOption `xml:"option"`
+}
+
+// ConfigOption is authentication option
+type ConfigOption struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// ConfigRaw contains raw XML of configuration section
+type ConfigRaw struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Data    string     `xml:",innerxml"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseConfig parses Icecast configuration
+func ParseConfig(data []byte) (*Config, error) {
+	return DecodeConfig(bytes.NewReader(data))
+}
+
+// DecodeConfig decodes Icecast configuration from given reader
+func DecodeConfig(r io.Reader) (*Config, error) {
+	config := &Config{}
+	err := xml.NewDecoder(r).Decode(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// ReadConfig reads and parses Icecast configuration file
+func ReadConfig(file string) (*Config, error) {
+	fd, err := os.Open(file)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read configuration: %w", err)
+	}
+
+	defer fd.Close()
+
+	return DecodeConfig(fd)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Marshal encodes configuration to XML
+func (c *Config) Marshal() ([]byte, error) {
+	if c == nil {
+		return nil, fmt.Errorf("Configuration is nil")
+	}
+
+	data, err := xml.MarshalIndent(c, "", "  ")
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't encode configuration: %w", err)
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// Write writes configuration to file
+func (c *Config) Write(file string, perms os.FileMode) error {
+	data, err := c.Marshal()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, perms)
+}
+
+// GetMount returns configuration for mount with given name
+func (c *Config) GetMount(mount string) *ConfigMount {
+	if c == nil {
+		return nil
+	}
+
+	mount = normalizeMount(mount)
+
+	for _, m := range c.Mounts {
+		if normalizeMount(m.MountName) == mount {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// Validate validates configuration and returns all found problems
+func (c *Config) Validate() error {
+	if c == nil {
+		return fmt.Errorf("Configuration is nil")
+	}
+
+	var errs []error
+
+	switch {
+	case c.Authentication == nil:
+		errs = append(errs, fmt.Errorf("Authentication section is missing"))
+	default:
+		if c.Authentication.SourcePassword == "" {
+			errs = append(errs, fmt.Errorf("Source password is empty"))
+		}
+
+		if c.Authentication.AdminPassword == "" {
+			errs = append(errs, fmt.Errorf("Admin password is empty"))
+		}
+
+		if c.Authentication.RelayUser != "" && c.Authentication.RelayPassword == "" {
+			errs = append(errs, fmt.Errorf("Relay password is empty"))
+		}
+	}
+
+	if len(c.ListenSockets) == 0 {
+		errs = append(errs, fmt.Errorf("No listen sockets defined"))
+	}
+
+	ports := make(map[string]bool)
+
+	for _, s := range c.ListenSockets {
+		if !isValidPort(s.Port) {
+			errs = append(errs, fmt.Errorf("Listen socket has invalid port %d", s.Port))
+			continue
+		}
+
+		addr := fmt.Sprintf("%s:%d", s.BindAddress, s.Port)
+
+		if ports[addr] {
+			errs = append(errs, fmt.Errorf("Listen socket %s is defined more than once", addr))
+		}
+
+		ports[addr] = true
+	}
+
+	relays := make(map[string]bool)
+
+	for _, r := range c.Relays {
+		if r.Server == "" {
+			errs = append(errs, fmt.Errorf("Relay for mount %q has no server", r.Mount))
+		}
+
+		if !isValidPort(r.Port) {
+			errs = append(errs, fmt.Errorf("Relay for mount %q has invalid port %d", r.Mount, r.Port))
+		}
+
+		if r.Username != "" && r.Password == "" {
+			errs = append(errs, fmt.Errorf("Relay for mount %q has no password", r.Mount))
+		}
+
+		local := r.LocalMount
+
+		if local == "" {
+			local = r.Mount
+		}
+
+		if relays[local] {
+			errs = append(errs, fmt.Errorf("Relay for mount %q is defined more than once", local))
+		}
+
+		relays[local] = true
+	}
+
+	// Mount blocks can configure relayed mounts, so they are checked separately
+	mounts := make(map[string]bool)
+
+	for _, m := range c.Mounts {
+		if m.Type == MOUNT_TYPE_DEFAULT {
+			continue
+		}
+
+		if m.MountName == "" {
+			errs = append(errs, fmt.Errorf("Mount has no name"))
+			continue
+		}
+
+		if !strings.HasPrefix(m.MountName, "/") {
+			errs = append(errs, fmt.Errorf("Mount %q must start with /", m.MountName))
+		}
+
+		if m.Username != "" && m.Password == "" {
+			errs = append(errs, fmt.Errorf("Mount %q has username but no password", m.MountName))
+		}
+
+		if m.MaxListeners != nil && *m.MaxListeners < -1 {
+			errs = append(errs, fmt.Errorf("Mount %q has invalid max-listeners value %d", m.MountName, *m.MaxListeners))
+		}
+
+		if m.FallbackMount != "" && m.FallbackMount == m.MountName {
+			errs = append(errs, fmt.Errorf("Mount %q uses itself as fallback", m.MountName))
+		}
+
+		if m.Authentication != nil && m.Authentication.Type == "" {
+			errs = append(errs, fmt.Errorf("Mount %q has authentication without type", m.MountName))
+		}
+
+		if mounts[m.MountName] {
+			errs = append(errs, fmt.Errorf("Mount %q is defined more than once", m.MountName))
+		}
+
+		mounts[m.MountName] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Option returns value of authentication option with given name
+func (a *ConfigMountAuthentication) Option(name string) string {
+	if a == nil {
+		return ""
+	}
+
+	for _, o := range a.Options {
+		if o.Name == name {
+			return o.Value
+		}
+	}
+
+	return ""
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsSet returns true if flag is set
+func (f Flag) IsSet() bool {
+	return f != FLAG_UNSET
+}
+
+// IsEnabled returns true if flag is enabled
+func (f Flag) IsEnabled() bool {
+	return f == FLAG_YES
+}
+
+// MarshalText encodes flag to text
+func (f Flag) MarshalText() ([]byte, error) {
+	if f == FLAG_YES {
+		return []byte("1"), nil
+	}
+
+	return []byte("0"), nil
+}
+
+// UnmarshalText decodes flag from text
+func (f *Flag) UnmarshalText(data []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(data))) {
+	case "1", "yes", "true":
+		*f = FLAG_YES
+	case "0", "no", "false":
+		*f = FLAG_NO
+	default:
+		return fmt.Errorf("Invalid flag value %q", string(data))
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isValidPort returns true if given port number is valid
+func isValidPort(port int) bool {
+	return port > 0 && port <= 65535
+}
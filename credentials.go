@@ -0,0 +1,422 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	MOUNT_PERM_METADATA MountPermission = 1 << iota // Updating metadata
+	MOUNT_PERM_FALLBACK                             // Updating fallback
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Credentials contains admin credentials
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CredentialProvider provides credentials for API requests. Provider is
+// consulted before every request, so credentials can be changed without
+// recreating API client.
+type CredentialProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// CredentialsFunc is function which implements CredentialProvider
+type CredentialsFunc func() (Credentials, error)
+
+// MountPermission is operation allowed with mount credentials
+type MountPermission uint8
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// mountCredentials contains mount credentials and allowed operations
+type mountCredentials struct {
+	provider CredentialProvider
+	perms    MountPermission
+}
+
+// staticCredentials is provider with fixed credentials
+type staticCredentials struct {
+	creds Credentials
+}
+
+// envCredentials is provider which reads credentials from environment variables
+type envCredentials struct {
+	userVar     string
+	passwordVar string
+}
+
+// fileCredentials is provider which reads credentials from file and reloads
+// them when file is changed
+type fileCredentials struct {
+	file    string
+	parser  func(data []byte) (Credentials, error)
+	creds   Credentials
+	err     error
+	modTime time.Time
+	size    int64
+	mu      sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrNilCredentials    = errors.New("Credential provider is nil")
+	ErrNoCredentials     = errors.New("Credentials not found")
+	ErrEmptyNetrcMachine = errors.New("Netrc machine name is empty")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewAPIWithCredentials creates new API struct with given credential provider
+func NewAPIWithCredentials(url string, provider CredentialProvider) (*API, error) {
+	switch {
+	case url == "":
+		return nil, ErrEmptyURL
+	case provider == nil:
+		return nil, ErrNilCredentials
+	}
+
+	api := newAPI(url)
+	api.credentials = provider
+
+	return api, nil
+}
+
+// SetCredentials sets credential provider used for requests
+func (api *API) SetCredentials(provider CredentialProvider) error {
+	if provider == nil {
+		return ErrNilCredentials
+	}
+
+	api.mu.Lock()
+	api.credentials = provider
+	api.mu.Unlock()
+
+	return nil
+}
+
+// SetMountCredentials sets source credentials for given mount which are used
+// instead of admin credentials for updating metadata. Additional operations can
+// be allowed using permissions (e.g. MOUNT_PERM_FALLBACK).
+func (api *API) SetMountCredentials(mount string, provider CredentialProvider, perms ...MountPermission) error {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return err
+	}
+
+	if provider == nil {
+		return ErrNilCredentials
+	}
+
+	mc := &mountCredentials{provider: provider, perms: MOUNT_PERM_METADATA}
+
+	for _, p := range perms {
+		mc.perms |= p
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if api.mountCredentials == nil {
+		api.mountCredentials = make(map[string]*mountCredentials)
+	}
+
+	api.mountCredentials[mount] = mc
+
+	return nil
+}
+
+// RemoveMountCredentials removes source credentials for given mount
+func (api *API) RemoveMountCredentials(mount string) {
+	api.mu.Lock()
+	delete(api.mountCredentials, normalizeMount(mount))
+	api.mu.Unlock()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// StaticCredentials creates provider with fixed credentials
+func StaticCredentials(user, password string) CredentialProvider {
+	return &staticCredentials{Credentials{User: user, Password: password}}
+}
+
+// EnvCredentials creates provider which reads credentials from given environment
+// variables
+func EnvCredentials(userVar, passwordVar string) CredentialProvider {
+	return &envCredentials{userVar: userVar, passwordVar: passwordVar}
+}
+
+// FileCredentials creates provider which reads credentials from file. File must
+// contain credentials in "user:password" format. File is reloaded when it is
+// changed.
+func FileCredentials(file string) CredentialProvider {
+	return &fileCredentials{file: file, parser: parseCredentialsFile}
+}
+
+// NetrcCredentials creates provider which reads credentials for given machine
+// from netrc file. If file is empty, $NETRC or ~/.netrc is used. File is
+// reloaded when it is changed.
+func NetrcCredentials(file, machine string) CredentialProvider {
+	if file == "" {
+		file = getNetrcFile()
+	}
+
+	return &fileCredentials{
+		file: file,
+		parser: func(data []byte) (Credentials, error) {
+			return parseNetrc(data, machine)
+		},
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Credentials returns credentials from function
+func (f CredentialsFunc) Credentials() (Credentials, error) {
+	return f()
+}
+
+// Validate returns error if credentials are incomplete
+func (c Credentials) Validate() error {
+	switch {
+	case c.User == "":
+		return ErrEmptyUser
+	case c.Password == "":
+		return ErrEmptyPassword
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Credentials returns fixed credentials
+func (p *staticCredentials) Credentials() (Credentials, error) {
+	return p.creds, nil
+}
+
+// Credentials returns credentials from environment variables
+func (p *envCredentials) Credentials() (Credentials, error) {
+	creds := Credentials{
+		User:     os.Getenv(p.userVar),
+		Password: os.Getenv(p.passwordVar),
+	}
+
+	if creds.User == "" || creds.Password == "" {
+		return Credentials{}, fmt.Errorf(
+			"%w in environment variables %s and %s",
+			ErrNoCredentials, p.userVar, p.passwordVar,
+		)
+	}
+
+	return creds, nil
+}
+
+// Credentials returns credentials from file
+func (p *fileCredentials) Credentials() (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.file)
+
+	if err != nil {
+		return Credentials{}, fmt.Errorf("Can't read credentials file: %w", err)
+	}
+
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.creds, p.err
+	}
+
+	data, err := os.ReadFile(p.file)
+
+	if err != nil {
+		return Credentials{}, fmt.Errorf("Can't read credentials file: %w", err)
+	}
+
+	p.creds, p.err = p.parser(data)
+	p.modTime, p.size = info.ModTime(), info.Size()
+
+	if p.err != nil {
+		p.err = fmt.Errorf("Can't parse credentials file %s: %w", p.file, p.err)
+	}
+
+	return p.creds, p.err
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getCredentials returns credentials for request
+func (api *API) getCredentials(call *APICall) (Credentials, error) {
+	provider := api.getMountCredentials(call)
+
+	if provider == nil {
+		api.mu.RLock()
+		provider = api.credentials
+		api.mu.RUnlock()
+	}
+
+	if provider == nil {
+		return Credentials{}, ErrNilCredentials
+	}
+
+	creds, err := provider.Credentials()
+
+	if err != nil {
+		return Credentials{}, fmt.Errorf("Can't get credentials: %w", err)
+	}
+
+	err = creds.Validate()
+
+	if err != nil {
+		return Credentials{}, fmt.Errorf("Invalid credentials: %w", err)
+	}
+
+	return creds, nil
+}
+
+// getMountCredentials returns mount credentials provider for request if
+// request operation is permitted for mount credentials
+func (api *API) getMountCredentials(call *APICall) CredentialProvider {
+	var perm MountPermission
+
+	switch call.Endpoint {
+	case "/metadata", "/admin.cgi":
+		perm = MOUNT_PERM_METADATA
+	case "/fallback":
+		perm = MOUNT_PERM_FALLBACK
+	default:
+		return nil
+	}
+
+	mount, _ := call.Query["mount"].(string)
+
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+
+	mc := api.mountCredentials[normalizeMount(mount)]
+
+	if mc == nil || mc.perms&perm == 0 {
+		return nil
+	}
+
+	return mc.provider
+}
+
+// parseCredentialsFile parses credentials file with "user:password" line
+func parseCredentialsFile(data []byte) (Credentials, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		user, password, ok := strings.Cut(line, ":")
+
+		if !ok {
+			return Credentials{}, fmt.Errorf("Credentials must be in \"user:password\" format")
+		}
+
+		return Credentials{User: user, Password: password}, nil
+	}
+
+	return Credentials{}, ErrNoCredentials
+}
+
+// parseNetrc parses netrc data and returns credentials for given machine
+func parseNetrc(data []byte, machine string) (Credentials, error) {
+	if machine == "" {
+		return Credentials{}, ErrEmptyNetrcMachine
+	}
+
+	var creds, defCreds *Credentials
+	var cur *Credentials
+
+	fields := strings.Fields(string(data))
+
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			cur = nil
+
+			if i+1 < len(fields) {
+				i++
+
+				if fields[i] == machine && creds == nil {
+					creds = &Credentials{}
+					cur = creds
+				}
+			}
+
+		case "default":
+			cur = nil
+
+			if defCreds == nil {
+				defCreds = &Credentials{}
+				cur = defCreds
+			}
+
+		case "login", "password", "account":
+			if i+1 >= len(fields) {
+				break
+			}
+
+			i++
+
+			if cur == nil {
+				continue
+			}
+
+			switch fields[i-1] {
+			case "login":
+				cur.User = fields[i]
+			case "password":
+				cur.Password = fields[i]
+			}
+
+		case "macdef":
+			// Macros definitions can contain any tokens, so we stop parsing
+			i = len(fields)
+		}
+	}
+
+	switch {
+	case creds != nil:
+		return *creds, nil
+	case defCreds != nil:
+		return *defCreds, nil
+	}
+
+	return Credentials{}, fmt.Errorf("%w for machine %q", ErrNoCredentials, machine)
+}
+
+// getNetrcFile returns path to default netrc file
+func getNetrcFile() string {
+	if file := os.Getenv("NETRC"); file != "" {
+		return file
+	}
+
+	home, _ := os.UserHomeDir()
+
+	return filepath.Join(home, ".netrc")
+}
@@ -8,6 +8,7 @@ package icecast
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +28,10 @@ type Stats struct {
 	Info    *ServerInfo
 	Stats   *ServerStats
 	Sources Sources
+
+	// Warnings contains problems found while converting data (e.g. dates in
+	// unsupported format)
+	Warnings []string
 }
 
 // ServerInfo contains basic info about Icecast Server
@@ -60,9 +65,10 @@ type Sources map[string]*Source
 
 // Source contains info about source
 type Source struct {
+	Mount           string
 	MetadataUpdated time.Time
 	StreamStarted   time.Time
-	Bitrate         string
+	Bitrate         Bitrate
 	Genre           string
 	ListenURL       string
 	SourceIP        string
@@ -87,8 +93,8 @@ type SourceInfo struct {
 // SourceStats contains source statistics
 type SourceStats struct {
 	Connected           int
-	IncomingBitrate     int
-	OutgoingBitrate     int
+	IncomingBitrate     Bitrate
+	OutgoingBitrate     Bitrate
 	ListenerConnections int
 	ListenerPeak        int
 	Listeners           int
@@ -101,10 +107,11 @@ type SourceStats struct {
 
 // AudioInfo contains basic info about stream
 type AudioInfo struct {
-	Bitrate    int
+	Bitrate    Bitrate
 	Channels   int
 	SampleRate int
 	CodecID    int
+	Codec      Codec
 	RawInfo    string
 }
 
@@ -163,6 +170,7 @@ type iceStats struct {
 	OutgoingKbitrate        int          `xml:"outgoing_kbitrate"`
 	ServerID                string       `xml:"server_id"`
 	ServerStart             string       `xml:"server_start"`
+	ServerStartISO8601      string       `xml:"server_start_iso8601"`
 	SourceClientConnections int          `xml:"source_client_connections"`
 	SourceRelayConnections  int          `xml:"source_relay_connections"`
 	SourceTotalConnections  int          `xml:"source_total_connections"`
@@ -189,7 +197,7 @@ type iceSource struct {
 	Bitrate             string `xml:"bitrate"`
 	Connected           int    `xml:"connected"`
 	Genre               string `xml:"genre"`
-	IceBitrate          int    `xml:"ice-bitrate"`
+	IceBitrate          string `xml:"ice-bitrate"`
 	IceChannels         int    `xml:"ice-channels"`
 	IceSamplerate       int    `xml:"ice-samplerate"`
 	IncomingBitrate     int    `xml:"incoming_bitrate"`
@@ -210,6 +218,7 @@ type iceSource struct {
 	SlowListeners       int    `xml:"slow_listeners"`
 	SourceIP            string `xml:"source_ip"`
 	StreamStart         string `xml:"stream_start"`
+	StreamStartISO8601  string `xml:"stream_start_iso8601"`
 	Subtype             string `xml:"subtype"`
 	TotalBytesRead      int    `xml:"total_bytes_read"`
 	TotalBytesSent      int    `xml:"total_bytes_sent"`
@@ -232,19 +241,27 @@ type iceResponse struct {
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// dateLayouts is list of date formats used by different Icecast variants
+var dateLayouts = []string{
+	"2/Jan/2006:15:04:05 -0700",      // Icecast 2.4 server_start and metadata_updated
+	"Mon, 2 Jan 2006 15:04:05 -0700", // Icecast 2.4 stream_start (RFC1123Z)
+	"Mon, 2 Jan 2006 15:04:05 MST",   // RFC1123
+	"2 Jan 06 15:04 -0700",           // RFC822Z
+	"2 Jan 06 15:04 MST",             // RFC822
+	"2006-01-02T15:04:05Z07:00",      // ISO8601 (RFC3339)
+	"2006-01-02T15:04:05-0700",       // ISO8601 without colon in zone offset
+	"Mon Jan _2 15:04:05 2006",       // ANSI C
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // GetSource tries to find source with given mount point
 func (s *Stats) GetSource(mount string) *Source {
 	if s.Sources == nil {
 		return nil
 	}
 
-	source, ok := s.Sources[mount]
-
-	if ok {
-		return source
-	}
-
-	return s.Sources["/"+mount]
+	return s.Sources[normalizeMount(mount)]
 }
 
 // ToQuery encodes meta to URL query
@@ -289,7 +306,6 @@ func convertStats(sv *iceStats) *Stats {
 	result := &Stats{
 		Admin:    sv.Admin,
 		Host:     sv.Host,
-		Started:  parseDate(sv.ServerStart),
 		Location: sv.Location,
 		Info: &ServerInfo{
 			ID:    sv.ServerID,
@@ -315,81 +331,128 @@ func convertStats(sv *iceStats) *Stats {
 		},
 	}
 
+	var warning string
+
+	result.Started, warning = parseDateField("server_start", sv.ServerStartISO8601, sv.ServerStart)
+
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+
 	if len(sv.SourcesData) != 0 {
 		result.Sources = make(Sources)
 	}
 
 	for _, s := range sv.SourcesData {
-		result.Sources[s.Mount] = &Source{
-			AudioInfo: &AudioInfo{
-				Bitrate:    s.AudioBitrate,
-				Channels:   s.AudioChannels,
-				SampleRate: s.AudioSamplerate,
-				CodecID:    s.AudioCodecID,
-				RawInfo:    s.AudioInfo,
-			},
-			IceAudioInfo: &AudioInfo{
-				Bitrate:    s.IceBitrate * 1000,
-				Channels:   s.IceChannels,
-				SampleRate: s.IceSamplerate,
-			},
-			Track: &TrackInfo{
-				Artist:      s.Artist,
-				Title:       s.Title,
-				Artwork:     s.Artwork,
-				MetadataURL: s.MetadataURL,
-				RawInfo:     s.YpCurrentlyPlaying,
-			},
-			Info: &SourceInfo{
-				Name:        s.ServerName,
-				Description: s.ServerDescription,
-				Type:        s.ServerType,
-				URL:         s.ServerURL,
-				SubType:     s.Subtype,
-			},
-			Stats: &SourceStats{
-				Connected:           s.Connected,
-				IncomingBitrate:     s.IncomingBitrate,
-				OutgoingBitrate:     s.OutgoingKbitrate * 1024,
-				ListenerConnections: s.ListenerConnections,
-				ListenerPeak:        s.ListenerPeak,
-				Listeners:           s.Listeners,
-				MaxListeners:        parseMax(s.MaxListeners),
-				QueueSize:           s.QueueSize,
-				SlowListeners:       s.SlowListeners,
-				TotalBytesRead:      s.TotalBytesRead,
-				TotalBytesSent:      s.TotalBytesSent,
-			},
-			Bitrate:         s.Bitrate,
-			Genre:           s.Genre,
-			ListenURL:       s.ListenURL,
-			MetadataUpdated: parseDate(s.MetadataUpdated),
-			StreamStarted:   parseDate(s.StreamStart),
-			Public:          s.Public == 1,
-			SourceIP:        s.SourceIP,
-			UserAgent:       s.UserAgent,
-		}
+		source, warnings := convertSource(s)
+		result.Sources[s.Mount] = source
+		result.Warnings = append(result.Warnings, warnings...)
+	}
 
-		if s.MPEGSamplerate != 0 && s.AudioSamplerate == 0 {
-			result.Sources[s.Mount].AudioInfo.SampleRate = s.MPEGSamplerate
-		}
+	return result
+}
 
-		if s.MPEGChannels != 0 && s.AudioChannels == 0 {
-			result.Sources[s.Mount].AudioInfo.Channels = s.MPEGChannels
-		}
+// convertSource converts source data from Icecast stats. Problems found while
+// converting data are returned as warnings.
+func convertSource(s *iceSource) (*Source, []string) {
+	codec := detectCodec(s)
+	bitrate := ParseBitrate(s.Bitrate, codec)
+
+	result := &Source{
+		Mount: s.Mount,
+		AudioInfo: &AudioInfo{
+			Bitrate:    NewBitrate(s.AudioBitrate),
+			Channels:   s.AudioChannels,
+			SampleRate: s.AudioSamplerate,
+			CodecID:    s.AudioCodecID,
+			Codec:      codec,
+			RawInfo:    s.AudioInfo,
+		},
+		IceAudioInfo: &AudioInfo{
+			Bitrate:    ParseBitrate(s.IceBitrate, codec),
+			Channels:   s.IceChannels,
+			SampleRate: s.IceSamplerate,
+		},
+		Track: &TrackInfo{
+			Artist:      s.Artist,
+			Title:       s.Title,
+			Artwork:     s.Artwork,
+			MetadataURL: s.MetadataURL,
+			RawInfo:     s.YpCurrentlyPlaying,
+		},
+		Info: &SourceInfo{
+			Name:        s.ServerName,
+			Description: s.ServerDescription,
+			Type:        s.ServerType,
+			URL:         s.ServerURL,
+			SubType:     s.Subtype,
+		},
+		Stats: &SourceStats{
+			Connected:           s.Connected,
+			IncomingBitrate:     NewBitrate(s.IncomingBitrate),
+			OutgoingBitrate:     NewBitrate(s.OutgoingKbitrate * 1024),
+			ListenerConnections: s.ListenerConnections,
+			ListenerPeak:        s.ListenerPeak,
+			Listeners:           s.Listeners,
+			MaxListeners:        parseMax(s.MaxListeners),
+			QueueSize:           s.QueueSize,
+			SlowListeners:       s.SlowListeners,
+			TotalBytesRead:      s.TotalBytesRead,
+			TotalBytesSent:      s.TotalBytesSent,
+		},
+		Bitrate:   bitrate,
+		Genre:     s.Genre,
+		ListenURL: s.ListenURL,
+		Public:    s.Public == 1,
+		SourceIP:  s.SourceIP,
+		UserAgent: s.UserAgent,
+	}
 
-		if s.AudioBitrate == 0 && isNum(s.Bitrate) {
-			bitrate, _ := strconv.Atoi(s.Bitrate)
+	if s.MPEGSamplerate != 0 && s.AudioSamplerate == 0 {
+		result.AudioInfo.SampleRate = s.MPEGSamplerate
+	}
 
-			if bitrate > 0 && bitrate < 1024 {
-				bitrate *= 1000
-			}
+	if s.MPEGChannels != 0 && s.AudioChannels == 0 {
+		result.AudioInfo.Channels = s.MPEGChannels
+	}
 
-			result.Sources[s.Mount].AudioInfo.Bitrate = bitrate
-		}
+	if s.AudioBitrate == 0 {
+		result.AudioInfo.Bitrate = bitrate
 	}
 
-	return result
+	var warning string
+	var warnings []string
+
+	result.MetadataUpdated, warning = parseDateField("metadata_updated", "", s.MetadataUpdated)
+
+	if warning != "" {
+		warnings = append(warnings, s.Mount+": "+warning)
+	}
+
+	result.StreamStarted, warning = parseDateField("stream_start", s.StreamStartISO8601, s.StreamStart)
+
+	if warning != "" {
+		warnings = append(warnings, s.Mount+": "+warning)
+	}
+
+	return result, warnings
+}
+
+// detectCodec detects source codec using codec ID or content type
+func detectCodec(s *iceSource) Codec {
+	codec := CodecFromID(s.AudioCodecID)
+	typeCodec := CodecFromType(s.ServerType, s.Subtype)
+
+	switch {
+	case codec == CODEC_UNKNOWN:
+		return typeCodec
+	case codec == CODEC_AAC && typeCodec == CODEC_HEAAC:
+		// FLV sound format ID is same for AAC and HE-AAC, so we refine it
+		// using content type (audio/aacp)
+		return CODEC_HEAAC
+	}
+
+	return codec
 }
 
 // parseMax parse value with possible "unlimited" value
@@ -403,13 +466,46 @@ func parseMax(data string) int {
 	return n
 }
 
-// isNum returns true if given string contains number
-func isNum(s string) bool {
-	return strings.Trim(s, "0123456789") == ""
+// parseDateField parses date from ISO8601 field if it's present or from legacy
+// field otherwise. Parsing problem is returned as warning.
+func parseDateField(name, isoDate, date string) (time.Time, string) {
+	var warnings []string
+
+	if strings.TrimSpace(isoDate) != "" {
+		result, err := parseDate(isoDate)
+
+		if err == nil {
+			return result, ""
+		}
+
+		warnings = append(warnings, fmt.Sprintf("Can't parse %s_iso8601: %v", name, err))
+	}
+
+	result, err := parseDate(date)
+
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Can't parse %s: %v", name, err))
+	}
+
+	return result, strings.Join(warnings, "; ")
 }
 
-// parseDate parses date
-func parseDate(date string) time.Time {
-	result, _ := time.Parse("2/Jan/2006:15:04:05 -0700", date)
-	return result
+// parseDate parses date in any format used by Icecast variants. Empty date is
+// parsed as zero time.
+func parseDate(date string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range dateLayouts {
+		result, err := time.Parse(layout, date)
+
+		if err == nil {
+			return result, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Unsupported date format %q", date)
 }
@@ -0,0 +1,402 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/req"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SHOUTcastAPI is SHOUTcast DNAS v2 API client
+type SHOUTcastAPI struct {
+	engine   *req.Engine
+	url      string
+	password string
+	sids     map[string]int
+	mu       sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type dnasStats struct {
+	TotalStreams     int           `xml:"TOTALSTREAMS"`
+	ActiveStreams    int           `xml:"ACTIVESTREAMS"`
+	CurrentListeners int           `xml:"CURRENTLISTENERS"`
+	PeakListeners    int           `xml:"PEAKLISTENERS"`
+	MaxListeners     int           `xml:"MAXLISTENERS"`
+	UniqueListeners  int           `xml:"UNIQUELISTENERS"`
+	Version          string        `xml:"VERSION"`
+	Streams          []*dnasStream `xml:"STREAMSTATS>STREAM"`
+}
+
+type dnasStream struct {
+	ID               int    `xml:"id,attr"`
+	CurrentListeners int    `xml:"CURRENTLISTENERS"`
+	PeakListeners    int    `xml:"PEAKLISTENERS"`
+	MaxListeners     int    `xml:"MAXLISTENERS"`
+	UniqueListeners  int    `xml:"UNIQUELISTENERS"`
+	ServerGenre      string `xml:"SERVERGENRE"`
+	ServerURL        string `xml:"SERVERURL"`
+	ServerTitle      string `xml:"SERVERTITLE"`
+	SongTitle        string `xml:"SONGTITLE"`
+	StreamHits       int    `xml:"STREAMHITS"`
+	StreamStatus     int    `xml:"STREAMSTATUS"`
+	StreamListed     int    `xml:"STREAMLISTED"`
+	StreamPath       string `xml:"STREAMPATH"`
+	StreamUptime     int    `xml:"STREAMUPTIME"`
+	Bitrate          int    `xml:"BITRATE"`
+	SampleRate       int    `xml:"SAMPLERATE"`
+	Content          string `xml:"CONTENT"`
+}
+
+type dnasListeners struct {
+	Listeners []*dnasListener `xml:"LISTENERS>LISTENER"`
+}
+
+type dnasListener struct {
+	Hostname    string `xml:"HOSTNAME"`
+	UserAgent   string `xml:"USERAGENT"`
+	ConnectTime int    `xml:"CONNECTTIME"`
+	UID         int    `xml:"UID"`
+	Referer     string `xml:"REFERER"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewSHOUTcastAPI creates new SHOUTcast DNAS v2 API client
+func NewSHOUTcastAPI(url, password string) (*SHOUTcastAPI, error) {
+	switch {
+	case url == "":
+		return nil, ErrEmptyURL
+	case password == "":
+		return nil, ErrEmptyPassword
+	}
+
+	engine := &req.Engine{}
+	engine.SetUserAgent("go-icecast", "3")
+
+	return &SHOUTcastAPI{
+		engine:   engine,
+		url:      strings.TrimRight(url, "/"),
+		password: password,
+		sids:     make(map[string]int),
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SetUserAgent set user-agent string based on app name and version
+func (api *SHOUTcastAPI) SetUserAgent(app, version string) {
+	api.engine.SetUserAgent(app, version, USER_AGENT)
+}
+
+// GetStats fetches info about server and all active streams
+func (api *SHOUTcastAPI) GetStats() (*Stats, error) {
+	stats := &dnasStats{}
+	err := api.doRequest("/statistics", req.Query{}, stats)
+
+	if err != nil {
+		return nil, err
+	}
+
+	api.updateSIDs(stats)
+
+	return api.convertStats(stats), nil
+}
+
+// ListMounts fetches info about active streams
+func (api *SHOUTcastAPI) ListMounts() ([]*Mount, error) {
+	stats, err := api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Mount
+
+	for _, source := range stats.Sources {
+		result = append(result, &Mount{
+			Path:        source.Mount,
+			Listeners:   source.Stats.Listeners,
+			Connected:   source.Stats.Connected,
+			ContentType: source.Info.Type,
+		})
+	}
+
+	return result, nil
+}
+
+// ListClients fetches list of listeners connected to given stream
+func (api *SHOUTcastAPI) ListClients(mount string) ([]*Listener, error) {
+	sid, err := api.getSID(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listeners := &dnasListeners{}
+	err = api.doRequest(
+		"/admin.cgi",
+		req.Query{"sid": sid, "mode": "viewxml", "page": 3},
+		listeners,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Listener
+
+	for _, l := range listeners.Listeners {
+		result = append(result, &Listener{
+			ID:        l.UID,
+			IP:        l.Hostname,
+			UserAgent: l.UserAgent,
+			Referer:   l.Referer,
+			Connected: l.ConnectTime,
+		})
+	}
+
+	return result, nil
+}
+
+// UpdateMeta updates meta of given stream
+func (api *SHOUTcastAPI) UpdateMeta(mount string, meta TrackMeta) error {
+	sid, err := api.getSID(mount)
+
+	if err != nil {
+		return err
+	}
+
+	query := meta.toCompatQuery()
+	query["sid"] = sid
+	query["mode"] = "updinfo"
+
+	return api.doRequest("/admin.cgi", query, nil)
+}
+
+// KillClient kicks listener with given UID from given stream
+func (api *SHOUTcastAPI) KillClient(mount string, id int) error {
+	sid, err := api.getSID(mount)
+
+	if err != nil {
+		return err
+	}
+
+	return api.doRequest(
+		"/admin.cgi",
+		req.Query{"sid": sid, "mode": "kickdst", "kickdst": id},
+		nil,
+	)
+}
+
+// KillSource kicks source of given stream
+func (api *SHOUTcastAPI) KillSource(mount string) error {
+	sid, err := api.getSID(mount)
+
+	if err != nil {
+		return err
+	}
+
+	return api.doRequest("/admin.cgi", req.Query{"sid": sid, "mode": "kicksrc"}, nil)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// doRequest sends request to DNAS and decodes XML response. If response is
+// nil, only response status is checked.
+func (api *SHOUTcastAPI) doRequest(endpoint string, query req.Query, response any) error {
+	query["pass"] = api.password
+
+	resp, err := api.engine.Get(req.Request{
+		URL:   api.url + endpoint,
+		Query: query,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Can't send request to SHOUTcast API: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if response == nil {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return parseCompatResponse(resp.StatusCode, data)
+	}
+
+	if resp.StatusCode != 200 {
+		return parseCompatResponse(resp.StatusCode, nil)
+	}
+
+	err = xml.NewDecoder(resp.Body).Decode(response)
+
+	if err != nil {
+		return fmt.Errorf("Can't parse API response: %w", err)
+	}
+
+	return nil
+}
+
+// getSID returns stream ID for given mount
+func (api *SHOUTcastAPI) getSID(mount string) (int, error) {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return 0, err
+	}
+
+	api.mu.Lock()
+	sid, ok := api.sids[mount]
+	api.mu.Unlock()
+
+	if ok {
+		return sid, nil
+	}
+
+	if sid := parseStreamSID(mount); sid > 0 {
+		return sid, nil
+	}
+
+	_, err = api.GetStats()
+
+	if err != nil {
+		return 0, err
+	}
+
+	api.mu.Lock()
+	sid, ok = api.sids[mount]
+	api.mu.Unlock()
+
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrSourceNotFound, mount)
+	}
+
+	return sid, nil
+}
+
+// updateSIDs updates mount to stream ID mapping
+func (api *SHOUTcastAPI) updateSIDs(stats *dnasStats) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.sids = make(map[string]int)
+
+	for _, s := range stats.Streams {
+		api.sids[getStreamMount(s)] = s.ID
+	}
+}
+
+// convertStats converts DNAS statistics to stats
+func (api *SHOUTcastAPI) convertStats(ds *dnasStats) *Stats {
+	now := time.Now()
+	result := &Stats{
+		Info: &ServerInfo{ID: "SHOUTcast Server " + ds.Version},
+		Stats: &ServerStats{
+			Listeners: ds.CurrentListeners,
+			Sources:   ds.ActiveStreams,
+		},
+	}
+
+	for _, s := range ds.Streams {
+		if s.StreamStatus != 1 {
+			continue
+		}
+
+		if result.Sources == nil {
+			result.Sources = make(Sources)
+		}
+
+		mount := getStreamMount(s)
+		codec := CodecFromType(s.Content, "")
+		bitrate := NewBitrate(s.Bitrate * 1000)
+		artist, title, _ := strings.Cut(s.SongTitle, " - ")
+
+		if title == "" {
+			artist, title = "", s.SongTitle
+		}
+
+		maxListeners := s.MaxListeners
+
+		if maxListeners == 0 {
+			maxListeners = -1
+		}
+
+		result.Sources[mount] = &Source{
+			Mount: mount,
+			AudioInfo: &AudioInfo{
+				Bitrate:    bitrate,
+				SampleRate: s.SampleRate,
+				Codec:      codec,
+			},
+			IceAudioInfo: &AudioInfo{},
+			Track: &TrackInfo{
+				Artist:  artist,
+				Title:   title,
+				RawInfo: s.SongTitle,
+			},
+			Info: &SourceInfo{
+				Name: s.ServerTitle,
+				Type: s.Content,
+				URL:  s.ServerURL,
+			},
+			Stats: &SourceStats{
+				Connected:           s.StreamUptime,
+				ListenerConnections: s.StreamHits,
+				ListenerPeak:        s.PeakListeners,
+				Listeners:           s.CurrentListeners,
+				MaxListeners:        maxListeners,
+			},
+			Bitrate:       bitrate,
+			Genre:         s.ServerGenre,
+			ListenURL:     api.url + mount,
+			StreamStarted: now.Add(-time.Duration(s.StreamUptime) * time.Second).Truncate(time.Second),
+			Public:        s.StreamListed == 1,
+		}
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getStreamMount returns mount for given stream
+func getStreamMount(s *dnasStream) string {
+	mount := strings.TrimRight(strings.TrimSpace(s.StreamPath), "/")
+
+	if mount == "" {
+		return "/stream/" + strconv.Itoa(s.ID)
+	}
+
+	return normalizeMount(mount)
+}
+
+// parseStreamSID parses stream ID from mount in "/stream/{sid}" format
+func parseStreamSID(mount string) int {
+	sid, ok := strings.CutPrefix(strings.TrimRight(mount, "/"), "/stream/")
+
+	if !ok {
+		return 0
+	}
+
+	id, err := strconv.Atoi(sid)
+
+	if err != nil || id <= 0 {
+		return 0
+	}
+
+	return id
+}
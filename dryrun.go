@@ -0,0 +1,331 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	PLAN_ACTION_KILL_SOURCE     = "killsource"
+	PLAN_ACTION_KILL_CLIENT     = "killclient"
+	PLAN_ACTION_MOVE_CLIENTS    = "moveclients"
+	PLAN_ACTION_UPDATE_FALLBACK = "fallback"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DryRun is API client which validates destructive operations and returns plan
+// instead of executing them
+type DryRun struct {
+	api *API
+}
+
+// Plan describes effects of destructive operation
+type Plan struct {
+	// Action is operation name
+	Action string
+
+	// Mount is target mount
+	Mount string
+
+	// Destination is destination mount for moving listeners or new fallback
+	Destination string
+
+	// ClientID is ID of listener to kill
+	ClientID int
+
+	// AffectedListeners is number of listeners affected by operation
+	AffectedListeners int
+
+	// Description is human-readable description of operation
+	Description string
+
+	// Warnings contains possible problems with operation
+	Warnings []string
+
+	api *API
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrSourceNotFound = errors.New("Source does not exist")
+	ErrClientNotFound = errors.New("Client does not exist")
+	ErrSameMount      = errors.New("Source and destination mounts are the same")
+	ErrNilPlan        = errors.New("Plan is nil")
+	ErrUnknownAction  = errors.New("Unknown plan action")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DryRun returns client for planning destructive operations without executing them
+func (api *API) DryRun() *DryRun {
+	return &DryRun{api: api}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// KillSource returns plan for killing source with given mount point
+func (d *DryRun) KillSource(mount string) (*Plan, error) {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := d.api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := getPlanSource(stats, mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := d.newPlan(PLAN_ACTION_KILL_SOURCE, mount)
+	plan.AffectedListeners = source.Stats.Listeners
+	plan.Description = fmt.Sprintf(
+		"Source %s will be disconnected, %d listener(s) will be moved to fallback or disconnected",
+		mount, plan.AffectedListeners,
+	)
+
+	return plan, nil
+}
+
+// KillClient returns plan for killing client with given ID connected to given
+// mount point
+func (d *DryRun) KillClient(mount string, id int) (*Plan, error) {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := d.api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getPlanSource(stats, mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listeners, err := d.api.ListClients(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var listener *Listener
+
+	for _, l := range listeners {
+		if l.ID == id {
+			listener = l
+			break
+		}
+	}
+
+	if listener == nil {
+		return nil, fmt.Errorf("%w: %d on %s", ErrClientNotFound, id, mount)
+	}
+
+	plan := d.newPlan(PLAN_ACTION_KILL_CLIENT, mount)
+	plan.ClientID = id
+	plan.AffectedListeners = 1
+	plan.Description = fmt.Sprintf(
+		"Client %d (%s) will be disconnected from %s", id, listener.IP, mount,
+	)
+
+	return plan, nil
+}
+
+// MoveClients returns plan for moving clients from one source to another
+func (d *DryRun) MoveClients(mount, dest string) (*Plan, error) {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dest, err = validateMount(dest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mount == dest {
+		return nil, ErrSameMount
+	}
+
+	stats, err := d.api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := getPlanSource(stats, mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	destSource, err := getPlanSource(stats, dest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := d.newPlan(PLAN_ACTION_MOVE_CLIENTS, mount)
+	plan.Destination = dest
+	plan.AffectedListeners = source.Stats.Listeners
+	plan.Description = fmt.Sprintf(
+		"%d listener(s) will be moved from %s to %s",
+		plan.AffectedListeners, mount, dest,
+	)
+
+	if plan.AffectedListeners == 0 {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("Source %s has no listeners", mount))
+	}
+
+	maxListeners := destSource.Stats.MaxListeners
+
+	if maxListeners >= 0 && destSource.Stats.Listeners+plan.AffectedListeners > maxListeners {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"Destination %s has limit of %d listeners, %d listener(s) won't fit",
+			dest, maxListeners, destSource.Stats.Listeners+plan.AffectedListeners-maxListeners,
+		))
+	}
+
+	codec, destCodec := getCodecName(source), getCodecName(destSource)
+
+	if codec != "" && destCodec != "" && codec != destCodec {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"Sources have different formats (%s → %s)", codec, destCodec,
+		))
+	}
+
+	return plan, nil
+}
+
+// UpdateFallback returns plan for updating fallback of given mount source
+func (d *DryRun) UpdateFallback(mount, fallback string) (*Plan, error) {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fallback, err = validateMount(fallback)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mount == fallback {
+		return nil, ErrSameMount
+	}
+
+	stats, err := d.api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getPlanSource(stats, mount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := d.newPlan(PLAN_ACTION_UPDATE_FALLBACK, mount)
+	plan.Destination = fallback
+	plan.Description = fmt.Sprintf(
+		"Fallback of %s will be set to %s, listeners won't be affected until source disconnects",
+		mount, fallback,
+	)
+
+	if stats.Sources[fallback] == nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"Fallback %s is not active, listeners will be disconnected if source fails",
+			fallback,
+		))
+	}
+
+	return plan, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns plan description
+func (p *Plan) String() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.Description
+}
+
+// Apply executes planned operation using the same API method as a regular call
+func (p *Plan) Apply() error {
+	if p == nil || p.api == nil {
+		return ErrNilPlan
+	}
+
+	switch p.Action {
+	case PLAN_ACTION_KILL_SOURCE:
+		return p.api.KillSource(p.Mount)
+	case PLAN_ACTION_KILL_CLIENT:
+		return p.api.KillClient(p.Mount, p.ClientID)
+	case PLAN_ACTION_MOVE_CLIENTS:
+		return p.api.MoveClients(p.Mount, p.Destination)
+	case PLAN_ACTION_UPDATE_FALLBACK:
+		return p.api.UpdateFallback(p.Mount, p.Destination)
+	}
+
+	return fmt.Errorf("%w %q", ErrUnknownAction, p.Action)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newPlan creates new plan
+func (d *DryRun) newPlan(action, mount string) *Plan {
+	return &Plan{Action: action, Mount: mount, api: d.api}
+}
+
+// getPlanSource returns source with given mount from stats
+func getPlanSource(stats *Stats, mount string) (*Source, error) {
+	source := stats.Sources[mount]
+
+	if source == nil || source.Stats == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, mount)
+	}
+
+	return source, nil
+}
+
+// getCodecName returns name of source codec (empty if codec is unknown)
+func getCodecName(source *Source) string {
+	switch {
+	case source.AudioInfo != nil && source.AudioInfo.Codec != CODEC_UNKNOWN:
+		return source.AudioInfo.Codec.String()
+	case source.Info != nil:
+		return source.Info.Type
+	}
+
+	return ""
+}
@@ -0,0 +1,390 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	FAILOVER_EVENT_PRIMARY_DOWN FailoverEventType = iota + 1 // Primary is missing or unhealthy
+	FAILOVER_EVENT_PRIMARY_UP                                // Primary is back and healthy
+	FAILOVER_EVENT_SWITCHED                                  // Listeners moved to backup
+	FAILOVER_EVENT_RESTORED                                  // Listeners moved back to primary
+	FAILOVER_EVENT_ERROR                                     // Error while moving listeners
+)
+
+// FAILOVER_EVENT_LIMIT is max number of events stored in event log
+const FAILOVER_EVENT_LIMIT = 256
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// FailoverPair is pair of primary and backup mounts
+type FailoverPair struct {
+	Primary string
+	Backup  string
+}
+
+// FailoverEventType is type of failover event
+type FailoverEventType uint8
+
+// FailoverEvent contains info about failover event
+type FailoverEvent struct {
+	Type    FailoverEventType
+	Time    time.Time
+	Primary string
+	Backup  string
+	Message string
+	Err     error
+}
+
+// FailoverController moves listeners between primary and backup mounts
+type FailoverController struct {
+	// FailAfter is number of successive failed checks after which listeners
+	// are moved to backup
+	FailAfter int
+
+	// StableFor is period of time primary must stay healthy before listeners
+	// are moved back
+	StableFor time.Duration
+
+	// Health is health evaluator used for detecting unhealthy primary mounts.
+	// If nil, only disappearing of primary mount triggers failover.
+	Health *HealthEvaluator
+
+	// OnEvent is optional callback for failover events. Callback is called
+	// without holding controller lock, but it must not call Process or Check.
+	OnEvent func(e FailoverEvent)
+
+	api       *API
+	pairs     []*failoverState
+	events    []FailoverEvent
+	pending   []FailoverEvent
+	now       func() time.Time
+	mu        sync.Mutex
+	processMu sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// failoverState contains current state of failover pair
+type failoverState struct {
+	FailoverPair
+
+	failedOver  bool
+	failCount   int
+	stableSince time.Time
+}
+
+// failoverMove contains info about listeners move which must be performed
+type failoverMove struct {
+	pair    *failoverState
+	from    string // Empty if there are no listeners to move
+	to      string
+	restore bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var ErrNoPairs = errors.New("No failover pairs defined")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewFailoverController creates new failover controller for given mount pairs
+func NewFailoverController(api *API, pairs ...FailoverPair) (*FailoverController, error) {
+	if api == nil {
+		return nil, ErrNilAPI
+	}
+
+	if len(pairs) == 0 {
+		return nil, ErrNoPairs
+	}
+
+	c := &FailoverController{
+		FailAfter: 2,
+		StableFor: time.Minute,
+		Health:    NewHealthEvaluator(),
+		api:       api,
+		now:       time.Now,
+	}
+
+	for _, p := range pairs {
+		if p.Primary == "" || p.Backup == "" {
+			return nil, fmt.Errorf("Failover pair %q → %q has empty mount", p.Primary, p.Backup)
+		}
+
+		c.pairs = append(c.pairs, &failoverState{
+			FailoverPair: FailoverPair{
+				Primary: normalizeMount(p.Primary),
+				Backup:  normalizeMount(p.Backup),
+			},
+		})
+	}
+
+	return c, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Run runs checks with given interval until context is canceled. Check errors
+// are reported as FAILOVER_EVENT_ERROR events.
+func (c *FailoverController) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Check()
+
+		if err != nil {
+			c.mu.Lock()
+			c.addEvent(nil, FAILOVER_EVENT_ERROR, err, "Can't fetch stats")
+			events := c.flushEvents()
+			c.mu.Unlock()
+
+			c.notify(events)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches stats and updates state of all pairs
+func (c *FailoverController) Check() error {
+	stats, err := c.api.GetStats()
+
+	if err != nil {
+		return err
+	}
+
+	c.Process(stats)
+
+	return nil
+}
+
+// Process updates state of all pairs using given stats snapshot. Listeners are
+// moved and OnEvent callback is called without holding controller lock.
+func (c *FailoverController) Process(stats *Stats) {
+	if stats == nil {
+		return
+	}
+
+	c.processMu.Lock()
+	defer c.processMu.Unlock()
+
+	c.mu.Lock()
+
+	healthy := make(map[string]bool)
+
+	if c.Health != nil {
+		for _, r := range c.Health.Evaluate(stats) {
+			healthy[r.Mount] = r.State != HEALTH_CRITICAL
+		}
+	}
+
+	var moves []*failoverMove
+
+	for _, p := range c.pairs {
+		if m := c.processPair(p, stats, healthy); m != nil {
+			moves = append(moves, m)
+		}
+	}
+
+	events := c.flushEvents()
+	c.mu.Unlock()
+
+	c.notify(events)
+
+	for _, m := range moves {
+		var err error
+
+		if m.from != "" {
+			err = c.api.MoveClients(m.from, m.to)
+		}
+
+		c.mu.Lock()
+		c.finishMove(m, err)
+		events = c.flushEvents()
+		c.mu.Unlock()
+
+		c.notify(events)
+	}
+}
+
+// IsFailedOver returns true if listeners of given primary mount are moved to
+// backup
+func (c *FailoverController) IsFailedOver(primary string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range c.pairs {
+		if p.Primary == normalizeMount(primary) {
+			return p.failedOver
+		}
+	}
+
+	return false
+}
+
+// Events returns copy of event log
+func (c *FailoverController) Events() []FailoverEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]FailoverEvent(nil), c.events...)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns name of event type
+func (t FailoverEventType) String() string {
+	switch t {
+	case FAILOVER_EVENT_PRIMARY_DOWN:
+		return "primary-down"
+	case FAILOVER_EVENT_PRIMARY_UP:
+		return "primary-up"
+	case FAILOVER_EVENT_SWITCHED:
+		return "switched"
+	case FAILOVER_EVENT_RESTORED:
+		return "restored"
+	case FAILOVER_EVENT_ERROR:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processPair updates state of given pair and returns listeners move which
+// must be performed
+func (c *FailoverController) processPair(p *failoverState, stats *Stats, healthy map[string]bool) *failoverMove {
+	now := c.now()
+	primary := stats.Sources[p.Primary]
+	backup := stats.Sources[p.Backup]
+	isUp := primary != nil && (c.Health == nil || healthy[p.Primary])
+
+	if !p.failedOver {
+		if isUp {
+			p.failCount = 0
+			return nil
+		}
+
+		p.failCount++
+
+		if p.failCount == 1 {
+			c.addEvent(p, FAILOVER_EVENT_PRIMARY_DOWN, nil, "Primary mount is missing or unhealthy")
+		}
+
+		if p.failCount < max(c.FailAfter, 1) {
+			return nil
+		}
+
+		if backup == nil {
+			c.addEvent(p, FAILOVER_EVENT_ERROR, nil, "Backup mount is not available")
+			return nil
+		}
+
+		m := &failoverMove{pair: p, to: p.Backup}
+
+		if primary != nil {
+			m.from = p.Primary
+		}
+
+		return m
+	}
+
+	if !isUp {
+		p.stableSince = time.Time{}
+		return nil
+	}
+
+	if p.stableSince.IsZero() {
+		p.stableSince = now
+		c.addEvent(p, FAILOVER_EVENT_PRIMARY_UP, nil, "Primary mount is back")
+	}
+
+	if now.Sub(p.stableSince) < c.StableFor {
+		return nil
+	}
+
+	m := &failoverMove{pair: p, to: p.Primary, restore: true}
+
+	if backup != nil {
+		m.from = p.Backup
+	}
+
+	return m
+}
+
+// finishMove updates pair state using result of listeners move
+func (c *FailoverController) finishMove(m *failoverMove, err error) {
+	p := m.pair
+
+	switch {
+	case err != nil && m.restore:
+		c.addEvent(p, FAILOVER_EVENT_ERROR, err, "Can't move listeners back to primary")
+	case err != nil:
+		c.addEvent(p, FAILOVER_EVENT_ERROR, err, "Can't move listeners to backup")
+	case m.restore:
+		p.failedOver, p.stableSince = false, time.Time{}
+		c.addEvent(p, FAILOVER_EVENT_RESTORED, nil, "Listeners moved back to primary mount")
+	default:
+		p.failedOver, p.failCount, p.stableSince = true, 0, time.Time{}
+		c.addEvent(p, FAILOVER_EVENT_SWITCHED, nil, "Listeners moved to backup mount")
+	}
+}
+
+// addEvent adds new event to event log and queues it for OnEvent callback. Pair
+// can be nil for errors not related to any pair.
+func (c *FailoverController) addEvent(p *failoverState, t FailoverEventType, err error, msg string) {
+	e := FailoverEvent{
+		Type:    t,
+		Time:    c.now(),
+		Message: msg,
+		Err:     err,
+	}
+
+	if p != nil {
+		e.Primary, e.Backup = p.Primary, p.Backup
+	}
+
+	c.events = append(c.events, e)
+	c.pending = append(c.pending, e)
+
+	if len(c.events) > FAILOVER_EVENT_LIMIT {
+		c.events = c.events[len(c.events)-FAILOVER_EVENT_LIMIT:]
+	}
+}
+
+// flushEvents returns and clears queued events
+func (c *FailoverController) flushEvents() []FailoverEvent {
+	events := c.pending
+	c.pending = nil
+	return events
+}
+
+// notify calls OnEvent callback for given events
+func (c *FailoverController) notify(events []FailoverEvent) {
+	if c.OnEvent == nil {
+		return
+	}
+
+	for _, e := range events {
+		c.OnEvent(e)
+	}
+}
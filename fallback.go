@@ -0,0 +1,406 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// FallbackGraph is graph of fallbacks between mount points
+type FallbackGraph struct {
+	nodes map[string]*FallbackNode
+	mu    sync.RWMutex
+}
+
+// FallbackNode contains info about mount point in fallback graph
+type FallbackNode struct {
+	Mount       string
+	Fallback    string
+	ContentType string
+	Configured  bool // Mount is defined in configuration
+	Live        bool // Mount is currently mounted
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrFallbackCycle        = errors.New("Fallback chain contains a loop")
+	ErrFallbackDangling     = errors.New("Fallback points to unknown mount")
+	ErrFallbackIncompatible = errors.New("Fallback has incompatible content type")
+	ErrNilGraph             = errors.New("Fallback graph is nil")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewFallbackGraph creates new empty fallback graph
+func NewFallbackGraph() *FallbackGraph {
+	return &FallbackGraph{nodes: make(map[string]*FallbackNode)}
+}
+
+// NewFallbackGraphFromConfig creates new fallback graph using mounts from
+// configuration
+func NewFallbackGraphFromConfig(config *Config) *FallbackGraph {
+	g := NewFallbackGraph()
+	g.AddConfig(config)
+	return g
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AddConfig adds mounts and fallbacks from configuration to graph
+func (g *FallbackGraph) AddConfig(config *Config) {
+	if g == nil || config == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, m := range config.Mounts {
+		if m.Type == MOUNT_TYPE_DEFAULT || m.MountName == "" {
+			continue
+		}
+
+		node := g.getNode(m.MountName)
+		node.Configured = true
+
+		if m.FallbackMount != "" {
+			node.Fallback = normalizeMount(m.FallbackMount)
+		}
+
+		if m.ContentType != "" {
+			node.ContentType = m.ContentType
+		}
+	}
+
+	for _, r := range config.Relays {
+		mount := r.LocalMount
+
+		if mount == "" {
+			mount = r.Mount
+		}
+
+		if mount != "" {
+			g.getNode(mount).Configured = true
+		}
+	}
+}
+
+// AddMounts adds runtime info about mounted sources to graph
+func (g *FallbackGraph) AddMounts(mounts []*Mount) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, n := range g.nodes {
+		n.Live = false
+	}
+
+	for _, m := range mounts {
+		node := g.getNode(m.Path)
+		node.Live = true
+
+		if m.ContentType != "" {
+			node.ContentType = m.ContentType
+		}
+	}
+}
+
+// AddStats adds runtime info about sources from stats to graph
+func (g *FallbackGraph) AddStats(stats *Stats) {
+	if g == nil || stats == nil {
+		return
+	}
+
+	var mounts []*Mount
+
+	for path, source := range stats.Sources {
+		mount := &Mount{Path: path}
+
+		if source.Info != nil {
+			mount.ContentType = source.Info.Type
+		}
+
+		mounts = append(mounts, mount)
+	}
+
+	g.AddMounts(mounts)
+}
+
+// SetFallback sets fallback for given mount without any checks
+func (g *FallbackGraph) SetFallback(mount, fallback string) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	g.getNode(mount).Fallback = normalizeMount(fallback)
+	g.mu.Unlock()
+}
+
+// Node returns copy of node for given mount
+func (g *FallbackGraph) Node(mount string) *FallbackNode {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	node, ok := g.nodes[normalizeMount(mount)]
+
+	if !ok {
+		return nil
+	}
+
+	nodeCopy := *node
+
+	return &nodeCopy
+}
+
+// Mounts returns sorted slice with all mounts in graph
+func (g *FallbackGraph) Mounts() []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var result []string
+
+	for mount := range g.nodes {
+		result = append(result, mount)
+	}
+
+	slices.Sort(result)
+
+	return result
+}
+
+// Chain returns fallback chain for given mount (mount itself is not included).
+// Chain stops on the first repeated mount.
+func (g *FallbackGraph) Chain(mount string) []string {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	chain, _ := g.walk(normalizeMount(mount), nil)
+
+	return chain
+}
+
+// Validate checks whole graph for loops and fallbacks to unknown mounts
+func (g *FallbackGraph) Validate() error {
+	if g == nil {
+		return ErrNilGraph
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var errs []error
+
+	reported := make(map[string]bool)
+	mounts := make([]string, 0, len(g.nodes))
+
+	for mount := range g.nodes {
+		mounts = append(mounts, mount)
+	}
+
+	slices.Sort(mounts)
+
+	for _, mount := range mounts {
+		node := g.nodes[mount]
+
+		if node.Fallback == "" {
+			continue
+		}
+
+		if g.nodes[node.Fallback] == nil {
+			errs = append(errs, fmt.Errorf("%w: %s → %s", ErrFallbackDangling, mount, node.Fallback))
+		}
+
+		chain, loop := g.walk(mount, nil)
+
+		if !loop || reported[chain[len(chain)-1]] {
+			continue
+		}
+
+		for _, m := range chain {
+			reported[m] = true
+		}
+
+		errs = append(errs, fmt.Errorf(
+			"%w: %s → %s", ErrFallbackCycle, mount, strings.Join(chain, " → "),
+		))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Check checks if setting given fallback for mount is safe
+func (g *FallbackGraph) Check(mount, fallback string) error {
+	if g == nil {
+		return ErrNilGraph
+	}
+
+	mount, fallback = normalizeMount(mount), normalizeMount(fallback)
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	target := g.nodes[fallback]
+
+	if target == nil {
+		return fmt.Errorf("%w: %s → %s", ErrFallbackDangling, mount, fallback)
+	}
+
+	if mount == fallback {
+		return fmt.Errorf("%w: %s → %s", ErrFallbackCycle, mount, fallback)
+	}
+
+	chain, _ := g.walk(fallback, map[string]string{mount: fallback})
+
+	if index := slices.Index(chain, mount); index != -1 {
+		return fmt.Errorf(
+			"%w: %s → %s → %s", ErrFallbackCycle,
+			mount, fallback, strings.Join(chain[:index+1], " → "),
+		)
+	}
+
+	source := g.nodes[mount]
+
+	if source != nil && !isCompatibleContentType(source.ContentType, target.ContentType) {
+		return fmt.Errorf(
+			"%w: %s (%s) → %s (%s)", ErrFallbackIncompatible,
+			mount, source.ContentType, fallback, target.ContentType,
+		)
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// UpdateFallbackSafe updates fallback for given mount source only if
+// the change doesn't introduce a loop, doesn't point to unknown mount and
+// both mounts have compatible content types. Graph is refreshed with list of
+// currently mounted sources before the check and updated after the change.
+func (api *API) UpdateFallbackSafe(graph *FallbackGraph, mount, fallback string) error {
+	if graph == nil {
+		return ErrNilGraph
+	}
+
+	mounts, err := api.ListMounts()
+
+	if err != nil {
+		return err
+	}
+
+	graph.AddMounts(mounts)
+
+	err = graph.Check(mount, fallback)
+
+	if err != nil {
+		return err
+	}
+
+	err = api.UpdateFallback(mount, fallback)
+
+	if err != nil {
+		return err
+	}
+
+	graph.SetFallback(mount, fallback)
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getNode returns node for given mount and creates it if required
+func (g *FallbackGraph) getNode(mount string) *FallbackNode {
+	mount = normalizeMount(mount)
+	node := g.nodes[mount]
+
+	if node == nil {
+		node = &FallbackNode{Mount: mount}
+		g.nodes[mount] = node
+	}
+
+	return node
+}
+
+// walk follows fallbacks starting from given mount and returns chain and flag
+// which is true if chain contains loop
+func (g *FallbackGraph) walk(mount string, override map[string]string) ([]string, bool) {
+	var chain []string
+
+	visited := map[string]bool{mount: true}
+
+	for {
+		next, ok := override[mount]
+
+		if !ok && g.nodes[mount] != nil {
+			next = g.nodes[mount].Fallback
+		}
+
+		if next == "" {
+			return chain, false
+		}
+
+		chain = append(chain, next)
+
+		if visited[next] {
+			return chain, true
+		}
+
+		visited[next] = true
+		mount = next
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isCompatibleContentType returns true if streams with given content types can
+// replace each other
+func isCompatibleContentType(t1, t2 string) bool {
+	t1, t2 = normalizeContentType(t1), normalizeContentType(t2)
+	return t1 == "" || t2 == "" || t1 == t2
+}
+
+// normalizeContentType removes parameters from content type and maps aliases
+// to the same value
+func normalizeContentType(t string) string {
+	t, _, _ = strings.Cut(t, ";")
+	t = strings.ToLower(strings.TrimSpace(t))
+
+	switch t {
+	case "audio/ogg", "audio/vorbis", "application/x-ogg":
+		return "application/ogg"
+	case "audio/aacp", "audio/x-aac", "audio/mp4":
+		return "audio/aac"
+	case "audio/mp3", "audio/x-mpeg", "audio/mpeg3":
+		return "audio/mpeg"
+	}
+
+	return t
+}
@@ -0,0 +1,361 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/version"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	FLAVOUR_UNKNOWN   Flavour = iota
+	FLAVOUR_VANILLA           // Icecast
+	FLAVOUR_KH                // Icecast-KH
+	FLAVOUR_BETA              // Icecast 2.5 beta and other pre-release builds
+	FLAVOUR_SHOUTCAST         // SHOUTcast DNAS
+)
+
+const (
+	CAP_MANAGEAUTH      Capability = iota + 1 // Listener auth management (/admin/manageauth)
+	CAP_JSON_STATUS                           // JSON status page (/status-json.xsl)
+	CAP_MOVE_CLIENTS                          // Moving listeners between mounts
+	CAP_UPDATE_FALLBACK                       // Updating mount fallback
+	CAP_CODEC_ID                              // audio_codecid stats field
+	CAP_ISO8601_DATES                         // server_start_iso8601 and stream_start_iso8601 stats fields
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Flavour is server implementation
+type Flavour uint8
+
+// Capability is optional server feature
+type Capability uint8
+
+// ServerVersion contains info about server flavour and version
+type ServerVersion struct {
+	Flavour Flavour
+	Version version.Version // Semantic version, KH and SHOUTcast build is stored as build
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrUnsupported   = errors.New("Feature is not supported by server")
+	ErrUnknownServer = errors.New("Unknown server")
+)
+
+// capabilities is capabilities table with minimal supported version for every
+// flavour. Empty version means that feature is supported by all versions,
+// missing flavour means that feature is not supported at all.
+var capabilities = map[Capability]map[Flavour]string{
+	CAP_MANAGEAUTH: {
+		FLAVOUR_VANILLA: "2.3.0",
+		FLAVOUR_KH:      "",
+		FLAVOUR_BETA:    "",
+	},
+	CAP_JSON_STATUS: {
+		FLAVOUR_VANILLA: "2.4.0",
+		FLAVOUR_KH:      "2.4.0",
+		FLAVOUR_BETA:    "",
+	},
+	CAP_MOVE_CLIENTS: {
+		FLAVOUR_VANILLA: "",
+		FLAVOUR_KH:      "",
+		FLAVOUR_BETA:    "",
+	},
+	CAP_UPDATE_FALLBACK: {
+		FLAVOUR_VANILLA: "",
+		FLAVOUR_KH:      "",
+		FLAVOUR_BETA:    "",
+	},
+	CAP_CODEC_ID: {
+		FLAVOUR_KH: "",
+	},
+	CAP_ISO8601_DATES: {
+		FLAVOUR_VANILLA: "2.5.0",
+		FLAVOUR_BETA:    "",
+	},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ParseServerID parses server ID (e.g. "Icecast 2.4.0-kh12") into flavour and
+// version
+func ParseServerID(id string) (*ServerVersion, error) {
+	fields := strings.Fields(id)
+
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("%w %q", ErrUnknownServer, id)
+	}
+
+	var flavour Flavour
+
+	switch strings.ToLower(fields[0]) {
+	case "icecast":
+		flavour = FLAVOUR_VANILLA
+	case "shoutcast":
+		flavour = FLAVOUR_SHOUTCAST
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownServer, id)
+	}
+
+	var ver string
+
+	for _, f := range fields[1:] {
+		if f[0] >= '0' && f[0] <= '9' {
+			ver = f
+			break
+		}
+	}
+
+	if ver == "" {
+		return nil, fmt.Errorf("Can't find version in server ID %q", id)
+	}
+
+	base, suffix, _ := strings.Cut(ver, "-")
+	parts := strings.Split(base, ".")
+
+	if len(parts) > 4 {
+		return nil, fmt.Errorf("Can't parse version in server ID %q", id)
+	}
+
+	nums := make([]int, len(parts))
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse version in server ID %q", id)
+		}
+
+		nums[i] = n
+	}
+
+	nums = append(nums, 0, 0, 0)
+
+	var preRelease, build string
+
+	suffix = strings.ToLower(suffix)
+
+	switch {
+	case flavour == FLAVOUR_SHOUTCAST:
+		if len(parts) == 4 {
+			build = parts[3]
+		}
+
+	case strings.HasPrefix(suffix, "kh"):
+		flavour, build = FLAVOUR_KH, suffix
+
+	case suffix != "":
+		flavour, preRelease = FLAVOUR_BETA, suffix
+
+	case nums[0] == 2 && nums[1] == 4 && nums[2] == 99:
+		// Icecast 2.5 beta releases use 2.4.99.N versions
+		flavour, preRelease = FLAVOUR_BETA, "beta"+strconv.Itoa(nums[3])
+		nums[1], nums[2] = 5, 0
+	}
+
+	ver = fmt.Sprintf("%d.%d.%d", nums[0], nums[1], nums[2])
+
+	if preRelease != "" {
+		ver += "-" + preRelease
+	}
+
+	if build != "" {
+		ver += "+" + build
+	}
+
+	v, err := version.Parse(ver)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse version in server ID %q: %w", id, err)
+	}
+
+	return &ServerVersion{Flavour: flavour, Version: v}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Version parses server ID into flavour and version
+func (i *ServerInfo) Version() (*ServerVersion, error) {
+	if i == nil {
+		return nil, fmt.Errorf("%w: server info is empty", ErrUnknownServer)
+	}
+
+	return ParseServerID(i.ID)
+}
+
+// Supports returns true if server supports given feature. Servers with hidden
+// or custom ID are treated as Icecast with baseline set of features.
+func (i *ServerInfo) Supports(c Capability) bool {
+	v, err := i.Version()
+
+	if err != nil {
+		return isBaselineCapability(c)
+	}
+
+	return v.Supports(c)
+}
+
+// Require returns ErrUnsupported if server doesn't support given feature
+func (i *ServerInfo) Require(c Capability) error {
+	if i.Supports(c) {
+		return nil
+	}
+
+	if i == nil || i.ID == "" {
+		return fmt.Errorf("%w: %s", ErrUnsupported, c)
+	}
+
+	return fmt.Errorf("%w: %s (%s)", ErrUnsupported, c, i.ID)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Supports returns true if server version supports given feature
+func (v *ServerVersion) Supports(c Capability) bool {
+	if v == nil {
+		return false
+	}
+
+	minVersion, ok := capabilities[c][v.Flavour]
+
+	if !ok {
+		return false
+	}
+
+	if minVersion == "" {
+		return true
+	}
+
+	mv, err := version.Parse(minVersion)
+
+	if err != nil {
+		return false
+	}
+
+	return !v.Version.Less(mv)
+}
+
+// String returns server version as string
+func (v *ServerVersion) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.Flavour.String() + " " + v.Version.String()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns name of flavour
+func (f Flavour) String() string {
+	switch f {
+	case FLAVOUR_VANILLA:
+		return "Icecast"
+	case FLAVOUR_KH:
+		return "Icecast-KH"
+	case FLAVOUR_BETA:
+		return "Icecast Beta"
+	case FLAVOUR_SHOUTCAST:
+		return "SHOUTcast"
+	}
+
+	return "Unknown"
+}
+
+// String returns name of capability
+func (c Capability) String() string {
+	switch c {
+	case CAP_MANAGEAUTH:
+		return "manageauth"
+	case CAP_JSON_STATUS:
+		return "json-status"
+	case CAP_MOVE_CLIENTS:
+		return "moveclients"
+	case CAP_UPDATE_FALLBACK:
+		return "fallback"
+	case CAP_CODEC_ID:
+		return "audio_codecid"
+	case CAP_ISO8601_DATES:
+		return "iso8601-dates"
+	}
+
+	return "unknown"
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ServerInfo returns info about server. Info is fetched once and cached.
+func (api *API) ServerInfo() (*ServerInfo, error) {
+	api.mu.RLock()
+	info := api.info
+	api.mu.RUnlock()
+
+	if info != nil {
+		return info, nil
+	}
+
+	stats, err := api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats.Info, nil
+}
+
+// Supports returns true if server supports given feature
+func (api *API) Supports(c Capability) (bool, error) {
+	info, err := api.ServerInfo()
+
+	if err != nil {
+		return false, err
+	}
+
+	return info.Supports(c), nil
+}
+
+// Require returns ErrUnsupported if server doesn't support given feature
+func (api *API) Require(c Capability) error {
+	info, err := api.ServerInfo()
+
+	if err != nil {
+		return err
+	}
+
+	return info.Require(c)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// require checks if server supports feature required for operation. Only
+// server info cached by GetStats is used, so no additional requests are sent.
+// If there is no cached info, server is treated as Icecast with baseline set
+// of features.
+func (api *API) require(c Capability) error {
+	api.mu.RLock()
+	info := api.info
+	api.mu.RUnlock()
+
+	return info.Require(c)
+}
+
+// isBaselineCapability returns true if feature is supported by all Icecast
+// versions
+func isBaselineCapability(c Capability) bool {
+	minVersion, ok := capabilities[c][FLAVOUR_VANILLA]
+	return ok && minVersion == ""
+}
@@ -0,0 +1,313 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	HEALTH_OK HealthState = iota
+	HEALTH_WARNING
+	HEALTH_CRITICAL
+)
+
+const (
+	HEALTH_CHECK_BITRATE        = "bitrate"
+	HEALTH_CHECK_QUEUE          = "queue"
+	HEALTH_CHECK_SLOW_LISTENERS = "slow-listeners"
+	HEALTH_CHECK_METADATA       = "metadata"
+	HEALTH_CHECK_STALLED        = "stalled"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// HealthState is source health state
+type HealthState uint8
+
+// HealthThresholds contains thresholds used by health evaluator. Zero value
+// disables check.
+type HealthThresholds struct {
+	// BitrateWarning is min ratio of incoming bitrate to advertised bitrate
+	BitrateWarning float64
+
+	// BitrateCritical is critical ratio of incoming bitrate to advertised bitrate
+	BitrateCritical float64
+
+	// QueueGrowth is number of successive snapshots with growing queue size
+	// after which source is marked with warning (critical after twice as many)
+	QueueGrowth int
+
+	// SlowListenersGrowth is number of successive snapshots with growing
+	// number of slow listeners after which source is marked with warning
+	SlowListenersGrowth int
+
+	// MetadataStaleWarning is max age of metadata
+	MetadataStaleWarning time.Duration
+
+	// MetadataStaleCritical is critical age of metadata
+	MetadataStaleCritical time.Duration
+
+	// Stalled is number of successive snapshots without new incoming data
+	// after which source is marked as critical
+	Stalled int
+}
+
+// HealthEvaluator evaluates health of sources using successive stats snapshots
+type HealthEvaluator struct {
+	Thresholds HealthThresholds
+
+	history map[string]*healthHistory
+	mu      sync.Mutex
+}
+
+// HealthReport contains info about source health
+type HealthReport struct {
+	Mount  string
+	State  HealthState
+	Issues []HealthIssue
+	Time   time.Time
+}
+
+// HealthIssue contains info about failed health check
+type HealthIssue struct {
+	Check   string
+	State   HealthState
+	Message string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// healthHistory contains data from previous snapshots of source
+type healthHistory struct {
+	QueueSize      int
+	SlowListeners  int
+	TotalBytesRead int
+
+	QueueGrowth  int
+	SlowGrowth   int
+	StalledCount int
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DefaultHealthThresholds is default set of health thresholds
+var DefaultHealthThresholds = HealthThresholds{
+	BitrateWarning:       0.8,
+	BitrateCritical:      0.5,
+	QueueGrowth:          3,
+	SlowListenersGrowth:  3,
+	MetadataStaleWarning: time.Hour,
+	Stalled:              2,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewHealthEvaluator creates new health evaluator with default thresholds
+func NewHealthEvaluator() *HealthEvaluator {
+	return &HealthEvaluator{
+		Thresholds: DefaultHealthThresholds,
+		history:    make(map[string]*healthHistory),
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Evaluate evaluates health of all sources from given stats snapshot. Evaluator
+// must be called with every new snapshot, because some checks use previous data.
+func (e *HealthEvaluator) Evaluate(stats *Stats) []*HealthReport {
+	return e.evaluate(stats, time.Now())
+}
+
+// Reset removes all collected history
+func (e *HealthEvaluator) Reset() {
+	e.mu.Lock()
+	e.history = make(map[string]*healthHistory)
+	e.mu.Unlock()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns name of health state
+func (s HealthState) String() string {
+	switch s {
+	case HEALTH_OK:
+		return "OK"
+	case HEALTH_WARNING:
+		return "Warning"
+	case HEALTH_CRITICAL:
+		return "Critical"
+	}
+
+	return "Unknown"
+}
+
+// IsOK returns true if source is healthy
+func (r *HealthReport) IsOK() bool {
+	return r != nil && r.State == HEALTH_OK
+}
+
+// Reasons returns slice with messages of all issues
+func (r *HealthReport) Reasons() []string {
+	if r == nil {
+		return nil
+	}
+
+	var result []string
+
+	for _, issue := range r.Issues {
+		result = append(result, issue.Message)
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// evaluate evaluates health of all sources from given stats snapshot
+func (e *HealthEvaluator) evaluate(stats *Stats, now time.Time) []*HealthReport {
+	if stats == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.history == nil {
+		e.history = make(map[string]*healthHistory)
+	}
+
+	var result []*HealthReport
+
+	for mount, source := range stats.Sources {
+		result = append(result, e.evaluateSource(mount, source, now))
+	}
+
+	for mount := range e.history {
+		if stats.Sources[mount] == nil {
+			delete(e.history, mount)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b *HealthReport) int {
+		return strings.Compare(a.Mount, b.Mount)
+	})
+
+	return result
+}
+
+// evaluateSource evaluates health of given source
+func (e *HealthEvaluator) evaluateSource(mount string, source *Source, now time.Time) *HealthReport {
+	report := &HealthReport{Mount: mount, Time: now}
+	t := e.Thresholds
+
+	if source == nil || source.Stats == nil {
+		return report
+	}
+
+	prev := e.history[mount]
+	cur := &healthHistory{
+		QueueSize:      source.Stats.QueueSize,
+		SlowListeners:  source.Stats.SlowListeners,
+		TotalBytesRead: source.Stats.TotalBytesRead,
+	}
+
+	if prev != nil {
+		cur.QueueGrowth = countGrowth(prev.QueueGrowth, prev.QueueSize, cur.QueueSize)
+		cur.SlowGrowth = countGrowth(prev.SlowGrowth, prev.SlowListeners, cur.SlowListeners)
+
+		if cur.TotalBytesRead <= prev.TotalBytesRead {
+			cur.StalledCount = prev.StalledCount + 1
+		}
+	}
+
+	e.history[mount] = cur
+
+	// Ratio is checked only if both bitrates are known, because some servers
+	// (e.g. SHOUTcast) don't report incoming bitrate at all
+	if source.AudioInfo != nil && source.AudioInfo.Bitrate.BPS() > 0 &&
+		source.Stats.IncomingBitrate.BPS() > 0 {
+		advertised := source.AudioInfo.Bitrate.BPS()
+		incoming := source.Stats.IncomingBitrate.BPS()
+		ratio := float64(incoming) / float64(advertised)
+		msg := fmt.Sprintf(
+			"Incoming bitrate %d kbps is %.0f%% of advertised %d kbps",
+			incoming/1000, ratio*100, advertised/1000,
+		)
+
+		switch {
+		case t.BitrateCritical > 0 && ratio < t.BitrateCritical:
+			report.add(HEALTH_CHECK_BITRATE, HEALTH_CRITICAL, msg)
+		case t.BitrateWarning > 0 && ratio < t.BitrateWarning:
+			report.add(HEALTH_CHECK_BITRATE, HEALTH_WARNING, msg)
+		}
+	}
+
+	if t.QueueGrowth > 0 && cur.QueueGrowth >= t.QueueGrowth {
+		msg := fmt.Sprintf(
+			"Queue size is growing for %d snapshots (%d bytes)",
+			cur.QueueGrowth, cur.QueueSize,
+		)
+
+		if cur.QueueGrowth >= t.QueueGrowth*2 {
+			report.add(HEALTH_CHECK_QUEUE, HEALTH_CRITICAL, msg)
+		} else {
+			report.add(HEALTH_CHECK_QUEUE, HEALTH_WARNING, msg)
+		}
+	}
+
+	if t.SlowListenersGrowth > 0 && cur.SlowGrowth >= t.SlowListenersGrowth {
+		report.add(HEALTH_CHECK_SLOW_LISTENERS, HEALTH_WARNING, fmt.Sprintf(
+			"Number of slow listeners is growing for %d snapshots (%d listeners)",
+			cur.SlowGrowth, cur.SlowListeners,
+		))
+	}
+
+	if !source.MetadataUpdated.IsZero() {
+		age := now.Sub(source.MetadataUpdated)
+		msg := fmt.Sprintf("Metadata was not updated for %s", age.Truncate(time.Second))
+
+		switch {
+		case t.MetadataStaleCritical > 0 && age >= t.MetadataStaleCritical:
+			report.add(HEALTH_CHECK_METADATA, HEALTH_CRITICAL, msg)
+		case t.MetadataStaleWarning > 0 && age >= t.MetadataStaleWarning:
+			report.add(HEALTH_CHECK_METADATA, HEALTH_WARNING, msg)
+		}
+	}
+
+	if t.Stalled > 0 && cur.StalledCount >= t.Stalled {
+		report.add(HEALTH_CHECK_STALLED, HEALTH_CRITICAL, fmt.Sprintf(
+			"No incoming data for %d snapshots", cur.StalledCount,
+		))
+	}
+
+	return report
+}
+
+// add adds issue to report and updates report state
+func (r *HealthReport) add(check string, state HealthState, msg string) {
+	r.Issues = append(r.Issues, HealthIssue{Check: check, State: state, Message: msg})
+	r.State = max(r.State, state)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// countGrowth returns number of successive increases of value
+func countGrowth(count, prev, cur int) int {
+	if cur > prev {
+		return count + 1
+	}
+
+	return 0
+}
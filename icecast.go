@@ -11,6 +11,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/essentialkaos/ek/v13/req"
 )
@@ -24,10 +25,13 @@ const USER_AGENT = "go-icecast/3"
 
 // API is Icecast API client
 type API struct {
-	engine   *req.Engine
-	url      string
-	user     string
-	password string
+	engine           *req.Engine
+	credentials      CredentialProvider
+	mountCredentials map[string]*mountCredentials
+	interceptors     []Interceptor
+	info             *ServerInfo
+	url              string
+	mu               sync.RWMutex
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -36,6 +40,7 @@ var (
 	ErrEmptyURL      = errors.New("URL is empty")
 	ErrEmptyUser     = errors.New("Username is empty")
 	ErrEmptyPassword = errors.New("Password is empty")
+	ErrNilAPI        = errors.New("API client is nil")
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -51,10 +56,10 @@ func NewAPI(url, user, password string) (*API, error) {
 		return nil, ErrEmptyPassword
 	}
 
-	engine := &req.Engine{}
-	engine.SetUserAgent("go-icecast", "3")
+	api := newAPI(url)
+	api.credentials = StaticCredentials(user, password)
 
-	return &API{engine: engine, url: url, user: user, password: password}, nil
+	return api, nil
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -76,7 +81,13 @@ func (api *API) GetStats() (*Stats, error) {
 		return nil, err
 	}
 
-	return convertStats(stats), nil
+	result := convertStats(stats)
+
+	api.mu.Lock()
+	api.info = result.Info
+	api.mu.Unlock()
+
+	return result, nil
 }
 
 // ListMounts fetches info about mounted sources
@@ -94,9 +105,15 @@ func (api *API) ListMounts() ([]*Mount, error) {
 
 // ListClients fetches list of listeners connected to given mount point
 func (api *API) ListClients(mount string) ([]*Listener, error) {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return nil, err
+	}
+
 	listeners := &iceListeners{}
 
-	err := api.doRequest("/listclients", req.Query{"mount": mount}, listeners)
+	err = api.doRequest("/listclients", req.Query{"mount": mount}, listeners)
 
 	if err != nil {
 		return nil, err
@@ -107,26 +124,44 @@ func (api *API) ListClients(mount string) ([]*Listener, error) {
 
 // UpdateMeta updates meta for given mount source
 func (api *API) UpdateMeta(mount string, meta TrackMeta) error {
+	mount, err := validateMount(mount)
+
+	if err != nil {
+		return err
+	}
+
 	query := meta.ToQuery()
 	query["mode"] = "updinfo"
 	query["mount"] = mount
 
 	response := &iceResponse{}
 
-	err := api.doRequest("/metadata", query, response)
+	return api.doRequest("/metadata", query, response)
+}
+
+// UpdateFallback updates fallback for given mount source
+func (api *API) UpdateFallback(mount, fallback string) error {
+	mount, err := validateMount(mount)
 
 	if err != nil {
 		return err
 	}
 
-	return parseResponse(response)
-}
+	fallback, err = validateMount(fallback)
+
+	if err != nil {
+		return err
+	}
+
+	err = api.require(CAP_UPDATE_FALLBACK)
+
+	if err != nil {
+		return err
+	}
 
-// UpdateFallback updates fallback for given mount source
-func (api *API) UpdateFallback(mount, fallback string) error {
 	response := &iceResponse{}
 
-	err := api.doRequest(
+	return api.doRequest(
 		"/fallback",
 		req.Query{
 			"mount":    mount,
@@ -134,19 +169,31 @@ func (api *API) UpdateFallback(mount, fallback string) error {
 		},
 		response,
 	)
+}
+
+// MoveClients moves clients from one source to another
+func (api *API) MoveClients(mount, dest string) error {
+	mount, err := validateMount(mount)
 
 	if err != nil {
 		return err
 	}
 
-	return parseResponse(response)
-}
+	dest, err = validateMount(dest)
+
+	if err != nil {
+		return err
+	}
+
+	err = api.require(CAP_MOVE_CLIENTS)
+
+	if err != nil {
+		return err
+	}
 
-// MoveClients moves clients from one source to another
-func (api *API) MoveClients(mount, dest string) error {
 	response := &iceResponse{}
 
-	err := api.doRequest(
+	return api.doRequest(
 		"/moveclients",
 		req.Query{
 			"mount":       mount,
@@ -154,19 +201,19 @@ func (api *API) MoveClients(mount, dest string) error {
 		},
 		response,
 	)
+}
+
+// KillClient kills client with given ID connected to given mount point
+func (api *API) KillClient(mount string, id int) error {
+	mount, err := validateMount(mount)
 
 	if err != nil {
 		return err
 	}
 
-	return parseResponse(response)
-}
-
-// KillClient kills client with given ID connected to given mount point
-func (api *API) KillClient(mount string, id int) error {
 	response := &iceResponse{}
 
-	err := api.doRequest(
+	return api.doRequest(
 		"/killclient",
 		req.Query{
 			"mount": mount,
@@ -174,54 +221,84 @@ func (api *API) KillClient(mount string, id int) error {
 		},
 		response,
 	)
-
-	if err != nil {
-		return err
-	}
-
-	return parseResponse(response)
 }
 
 // KillSource kills the source with given mount point
 func (api *API) KillSource(mount string) error {
-	response := &iceResponse{}
-
-	err := api.doRequest("/killsource", req.Query{"mount": mount}, response)
+	mount, err := validateMount(mount)
 
 	if err != nil {
 		return err
 	}
 
-	return parseResponse(response)
+	response := &iceResponse{}
+
+	return api.doRequest("/killsource", req.Query{"mount": mount}, response)
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
-// doRequest sends request to Icecast API
+// newAPI creates new API struct without credentials
+func newAPI(url string) *API {
+	engine := &req.Engine{}
+	engine.SetUserAgent("go-icecast", "3")
+
+	return &API{engine: engine, url: url}
+}
+
+// doRequest sends request to API and decodes response
 func (api *API) doRequest(endpoint string, query req.Query, response any) error {
-	resp, err := api.engine.Get(req.Request{
-		URL:    api.url + "/admin" + endpoint,
-		Auth:   req.AuthBasic{api.user, api.password},
-		Query:  query,
-		Accept: req.CONTENT_TYPE_XML,
+	return api.intercept(endpoint, query, func(call *APICall) error {
+		resp, err := api.sendRequest(call)
+
+		if err != nil {
+			return err
+		}
+
+		defer resp.Body.Close()
+
+		xmlDec := xml.NewDecoder(resp.Body)
+		err = xmlDec.Decode(response)
+
+		if err != nil {
+			return fmt.Errorf("Can't parse API response: %w", err)
+		}
+
+		if r, ok := response.(*iceResponse); ok {
+			return parseResponse(r)
+		}
+
+		return nil
 	})
+}
+
+// sendRequest sends request to API
+func (api *API) sendRequest(call *APICall) (*req.Response, error) {
+	creds, err := api.getCredentials(call)
 
 	if err != nil {
-		return fmt.Errorf("Can't send request to Icecast API: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("API returned non-ok status code %d", resp.StatusCode)
+	resp, err := api.engine.Get(req.Request{
+		URL:    api.url + "/admin" + call.Endpoint,
+		Auth:   req.AuthBasic{creds.User, creds.Password},
+		Query:  call.Query,
+		Accept: req.CONTENT_TYPE_XML,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't send request to Icecast API: %w", err)
 	}
 
-	xmlDec := xml.NewDecoder(resp.Body)
-	err = xmlDec.Decode(response)
+	call.StatusCode = resp.StatusCode
 
-	if err != nil {
-		return fmt.Errorf("Can't parse API response: %w", err)
+	if resp.StatusCode != 200 {
+		resp.Discard()
+		return nil, fmt.Errorf("API returned non-ok status code %d", resp.StatusCode)
 	}
 
-	return nil
+	return resp, nil
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -8,13 +8,26 @@ package icecast
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/essentialkaos/ek/v13/req"
+
 	. "github.com/essentialkaos/check"
 )
 
@@ -121,12 +134,14 @@ func (s *IcecastSuite) TestGetStats(c *C) {
 
 	c.Assert(ics, NotNil)
 
-	c.Assert(ics.AudioInfo.Bitrate, Equals, 320000)
+	c.Assert(ics.AudioInfo.Bitrate.BPS(), Equals, 320000)
 	c.Assert(ics.AudioInfo.Channels, Equals, 2)
 	c.Assert(ics.AudioInfo.SampleRate, Equals, 48000)
 	c.Assert(ics.AudioInfo.RawInfo, Equals, "ice-samplerate=48000;ice-bitrate=Quality 0;ice-channels=2")
+	c.Assert(ics.AudioInfo.Codec, Equals, CODEC_VORBIS)
+	c.Assert(ics.AudioInfo.Values().Bitrate(), Equals, "Quality 0")
 
-	c.Assert(ics.IceAudioInfo.Bitrate, Equals, 320000)
+	c.Assert(ics.IceAudioInfo.Bitrate.BPS(), Equals, 320000)
 	c.Assert(ics.IceAudioInfo.Channels, Equals, 2)
 	c.Assert(ics.IceAudioInfo.SampleRate, Equals, 48000)
 	c.Assert(ics.IceAudioInfo.RawInfo, Equals, "")
@@ -142,8 +157,8 @@ func (s *IcecastSuite) TestGetStats(c *C) {
 	c.Assert(ics.Info.URL, Equals, "https://domain.com")
 
 	c.Assert(ics.Stats.Connected, Equals, 16)
-	c.Assert(ics.Stats.IncomingBitrate, Equals, 320000)
-	c.Assert(ics.Stats.OutgoingBitrate, Equals, 319042560)
+	c.Assert(ics.Stats.IncomingBitrate.BPS(), Equals, 320000)
+	c.Assert(ics.Stats.OutgoingBitrate.BPS(), Equals, 319042560)
 	c.Assert(ics.Stats.ListenerConnections, Equals, 20)
 	c.Assert(ics.Stats.ListenerPeak, Equals, 40)
 	c.Assert(ics.Stats.Listeners, Equals, 16)
@@ -153,7 +168,9 @@ func (s *IcecastSuite) TestGetStats(c *C) {
 	c.Assert(ics.Stats.TotalBytesRead, Equals, 4655111)
 	c.Assert(ics.Stats.TotalBytesSent, Equals, 1567151)
 
-	c.Assert(ics.Bitrate, Equals, "Quality 0")
+	c.Assert(ics.Bitrate.String(), Equals, "Quality 0")
+	c.Assert(ics.Bitrate.IsVBR(), Equals, true)
+	c.Assert(ics.Bitrate.BPS(), Equals, 64000)
 	c.Assert(ics.Genre, Equals, "Various Styles")
 	c.Assert(ics.ListenURL, Equals, "http://localhost:8000/source.ogg")
 	c.Assert(ics.MetadataUpdated.Unix(), Equals, int64(1587210604))
@@ -164,10 +181,13 @@ func (s *IcecastSuite) TestGetStats(c *C) {
 
 	ics = ic.GetSource("source1.aac")
 
-	c.Assert(ics.AudioInfo.Bitrate, Equals, 320000)
+	c.Assert(ics.AudioInfo.Bitrate.BPS(), Equals, 320000)
+	c.Assert(ics.AudioInfo.Bitrate.Kind, Equals, BITRATE_CONSTANT)
 	c.Assert(ics.AudioInfo.Channels, Equals, 1)
 	c.Assert(ics.AudioInfo.SampleRate, Equals, 32000)
 	c.Assert(ics.AudioInfo.CodecID, Equals, 10)
+	c.Assert(ics.AudioInfo.Codec, Equals, CODEC_AAC)
+	c.Assert(ics.Codec(), Equals, CODEC_AAC)
 
 	c.Assert(ic.GetSource("/source1.ogg"), NotNil)
 
@@ -315,203 +335,3314 @@ func (s *IcecastSuite) TestAux(c *C) {
 	c.Assert(parseResponse(nil), NotNil)
 }
 
-// ////////////////////////////////////////////////////////////////////////////////// //
+func (s *IcecastSuite) TestConfigParsing(c *C) {
+	config, err := ReadConfig("testdata/icecast.xml")
 
-func runHTTPServer(c *C, port string) {
-	server := &http.Server{
-		Handler:        http.NewServeMux(),
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
-	}
+	c.Assert(err, IsNil)
+	c.Assert(config, NotNil)
+
+	c.Assert(config.Location, Equals, "Earth")
+	c.Assert(config.Hostname, Equals, "localhost")
+	c.Assert(config.Fileserve.IsEnabled(), Equals, true)
+	c.Assert(config.Limits.Clients, Equals, 100)
+	c.Assert(config.Limits.BurstOnConnect.IsEnabled(), Equals, true)
+	c.Assert(config.Authentication.AdminUser, Equals, "admin")
+	c.Assert(config.Authentication.SourcePassword, Equals, "hackme")
+	c.Assert(config.ListenSockets, HasLen, 2)
+	c.Assert(config.ListenSockets[1].Port, Equals, 8443)
+	c.Assert(config.ListenSockets[1].SSL.IsEnabled(), Equals, true)
+	c.Assert(config.ListenSockets[1].Extra, HasLen, 1)
+	c.Assert(config.ListenSockets[1].Extra[0].XMLName.Local, Equals, "listen-backlog")
+	c.Assert(config.Relays, HasLen, 1)
+	c.Assert(config.Relays[0].LocalMount, Equals, "/different.ogg")
+	c.Assert(config.Relays[0].OnDemand.IsSet(), Equals, true)
+	c.Assert(config.Relays[0].OnDemand.IsEnabled(), Equals, false)
+	c.Assert(config.Relays[0].Extra, HasLen, 1)
+	c.Assert(config.Relays[0].Extra[0].XMLName.Local, Equals, "retry-delay")
+	c.Assert(config.Mounts, HasLen, 2)
+	c.Assert(config.Extra, HasLen, 1)
+	c.Assert(config.Limits.Extra, HasLen, 1)
+	c.Assert(config.Limits.Extra[0].XMLName.Local, Equals, "min-queue-size")
+	c.Assert(config.Authentication.Extra, HasLen, 1)
+	c.Assert(config.Authentication.Extra[0].XMLName.Local, Equals, "role")
+
+	m := config.GetMount("live.ogg")
+
+	c.Assert(m, NotNil)
+	c.Assert(m.Type, Equals, MOUNT_TYPE_NORMAL)
+	c.Assert(*m.MaxListeners, Equals, 1)
+	c.Assert(*config.GetMount("/autodj.ogg").MaxListeners, Equals, 0)
+	c.Assert(m.FallbackMount, Equals, "/autodj.ogg")
+	c.Assert(m.Intro, Equals, "/intro.ogg")
+	c.Assert(m.Charset, Equals, "ISO8859-1")
+	c.Assert(m.ContentType, Equals, "application/ogg")
+	c.Assert(m.Authentication.Type, Equals, "url")
+	c.Assert(m.Authentication.Option("listener_add"), Equals, "http://127.0.0.1/listener_add")
+	c.Assert(m.Authentication.Option("unknown"), Equals, "")
+	c.Assert(m.Extra, HasLen, 1)
+
+	c.Assert(config.GetMount("/unknown.ogg"), IsNil)
+	c.Assert(config.GetMount(" /live.ogg "), Equals, m)
+	c.Assert(config.Validate(), IsNil)
+
+	_, err = ReadConfig("testdata/unknown.xml")
+	c.Assert(err, NotNil)
 
-	listener, err := net.Listen("tcp", ":"+port)
+	_, err = ParseConfig([]byte("<icecast><fileserve>maybe</fileserve></icecast>"))
+	c.Assert(err, NotNil)
+}
 
-	if err != nil {
-		c.Fatal(err.Error())
-	}
+func (s *IcecastSuite) TestConfigMarshaling(c *C) {
+	config, err := ReadConfig("testdata/icecast.xml")
 
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/metadata", handlerMetadata)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/fallback", handlerFallback)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/listclients", handlerListClients)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/moveclients", handlerMoveClients)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/killclient", handlerKillClient)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/killsource", handlerKillSource)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/stats", handlerStats)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/listmounts", handlerListMounts)
-	server.Handler.(*http.ServeMux).HandleFunc("/admin/_garbage", handlerGarbageResponse)
+	c.Assert(err, IsNil)
 
-	err = server.Serve(listener)
+	config.Mounts[1].Public = FLAG_NO
 
-	if err != nil {
-		c.Fatal(err.Error())
-	}
+	file := c.MkDir() + "/icecast.xml"
+
+	c.Assert(config.Write(file, 0600), IsNil)
+
+	config, err = ReadConfig(file)
+
+	c.Assert(err, IsNil)
+	c.Assert(config.Validate(), IsNil)
+	c.Assert(config.Limits.QueueSize, Equals, 524288)
+	c.Assert(config.ListenSockets, HasLen, 2)
+	c.Assert(config.Mounts[0].Authentication.Option("auth_header"), Equals, "icecast-auth-user: 1")
+	c.Assert(config.Mounts[1].Public.IsSet(), Equals, true)
+	c.Assert(config.Mounts[1].Public.IsEnabled(), Equals, false)
+	c.Assert(config.Mounts[1].Hidden.IsSet(), Equals, false)
+	c.Assert(config.Mounts[1].MaxListeners, NotNil)
+	c.Assert(*config.Mounts[1].MaxListeners, Equals, 0)
+	c.Assert(config.ListenSockets[1].Extra[0].Data, Equals, "64")
+	c.Assert(config.Relays[0].Extra[0].Data, Equals, "30")
+	c.Assert(config.Extra, HasLen, 1)
+	c.Assert(config.Extra[0].XMLName.Local, Equals, "paths")
+	c.Assert(config.Limits.Extra, HasLen, 1)
+	c.Assert(config.Limits.Extra[0].Data, Equals, "65536")
+	c.Assert(config.Authentication.Extra, HasLen, 1)
+	c.Assert(config.Authentication.Extra[0].Attrs, HasLen, 2)
+
+	var nilConfig *Config
+
+	_, err = nilConfig.Marshal()
+	c.Assert(err, NotNil)
+	c.Assert(nilConfig.Write(file, 0600), NotNil)
+	c.Assert(nilConfig.Validate(), NotNil)
+	c.Assert(nilConfig.GetMount("/live.ogg"), IsNil)
 }
 
-func handlerMetadata(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
+func (s *IcecastSuite) TestConfigValidation(c *C) {
+	maxListeners := -5
+
+	config := &Config{
+		Authentication: &ConfigAuthentication{RelayUser: "relay"},
+		ListenSockets: []*ConfigListenSocket{
+			{Port: 8000}, {Port: 8000}, {Port: 70000},
+		},
+		Relays: []*ConfigRelay{
+			{Port: 0, Mount: "/relay.ogg", Username: "joe"},
+			{Server: "127.0.0.1", Port: 8000, Mount: "/other.ogg", LocalMount: "/relay.ogg"},
+		},
+		Mounts: []*ConfigMount{
+			{MountName: "/relay.ogg"},
+			{MountName: "live.ogg", Username: "john"},
+			{MountName: "/self.ogg", FallbackMount: "/self.ogg", MaxListeners: &maxListeners},
+			{MountName: "/auth.ogg", Authentication: &ConfigMountAuthentication{}},
+			{MountName: "/auth.ogg"},
+			{},
+			{Type: MOUNT_TYPE_DEFAULT},
+		},
 	}
 
-	mode := r.URL.Query().Get("mode")
-	mount := r.URL.Query().Get("mount")
-	artist := r.URL.Query().Get("artist")
-	title := r.URL.Query().Get("title")
+	err := config.Validate()
 
-	if mount == "/source99.ogg" {
-		w.WriteHeader(200)
-		w.Write(getResponseData("metadata_error.xml"))
-		return
-	}
+	c.Assert(err, NotNil)
 
-	switch {
-	case mode != "updinfo",
-		mount != "/source1.ogg",
-		artist == "",
-		title == "":
-		w.WriteHeader(400)
-		return
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+
+	c.Assert(errs, HasLen, 16)
+	c.Assert(errs[0], ErrorMatches, "Source password is empty")
+	c.Assert(errs[1], ErrorMatches, "Admin password is empty")
+	c.Assert(errs[2], ErrorMatches, "Relay password is empty")
+	c.Assert(errs[3], ErrorMatches, "Listen socket :8000 is defined more than once")
+	c.Assert(errs[4], ErrorMatches, "Listen socket has invalid port 70000")
+	c.Assert(errs[8], ErrorMatches, `Relay for mount "/relay.ogg" is defined more than once`)
+	c.Assert(errs[14], ErrorMatches, `Mount "/auth.ogg" is defined more than once`)
+
+	for _, err := range errs {
+		c.Assert(err, Not(ErrorMatches), `Mount "/relay.ogg" is defined more than once`)
 	}
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("metadata.xml"))
+	c.Assert((&Config{}).Validate(), ErrorMatches, "Authentication section is missing\nNo listen sockets defined")
+
+	var f Flag
+
+	c.Assert(f.UnmarshalText([]byte("yes")), IsNil)
+	c.Assert(f.IsEnabled(), Equals, true)
+	c.Assert(f.UnmarshalText([]byte("false")), IsNil)
+	c.Assert(f.IsEnabled(), Equals, false)
+	c.Assert(f.UnmarshalText([]byte("maybe")), NotNil)
 }
 
-func handlerFallback(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
-	}
+func (s *IcecastSuite) TestFallbackGraph(c *C) {
+	config, err := ReadConfig("testdata/icecast.xml")
 
-	mount := r.URL.Query().Get("mount")
-	fallback := r.URL.Query().Get("fallback")
+	c.Assert(err, IsNil)
 
-	if mount != "/source1.ogg" || fallback == "" {
-		w.WriteHeader(400)
-		return
-	}
+	g := NewFallbackGraphFromConfig(config)
+
+	c.Assert(g.Mounts(), DeepEquals, []string{"/autodj.ogg", "/different.ogg", "/live.ogg"})
+	c.Assert(g.Validate(), IsNil)
+	c.Assert(g.Chain("live.ogg"), DeepEquals, []string{"/autodj.ogg"})
+	c.Assert(g.Node("/live.ogg").Configured, Equals, true)
+	c.Assert(g.Node("/unknown.ogg"), IsNil)
+
+	c.Assert(g.Check("/autodj.ogg", "/live.ogg"), ErrorMatches, "Fallback chain contains a loop: /autodj.ogg → /live.ogg → /autodj.ogg")
+	c.Assert(g.Check("/autodj.ogg", "/autodj.ogg"), ErrorMatches, "Fallback chain contains a loop: .*")
+	c.Assert(g.Check("/autodj.ogg", "/unknown.ogg"), ErrorMatches, "Fallback points to unknown mount: .*")
+	c.Assert(g.Check("/different.ogg", "/autodj.ogg"), IsNil)
+
+	g.AddStats(&Stats{Sources: Sources{
+		"/different.ogg": &Source{Info: &SourceInfo{Type: "audio/mpeg"}},
+	}})
+
+	c.Assert(g.Node("/different.ogg").Live, Equals, true)
+	c.Assert(g.Check("/different.ogg", "/autodj.ogg"), ErrorMatches, "Fallback has incompatible content type: .*")
+
+	g.SetFallback("/autodj.ogg", "/b.ogg")
+	g.SetFallback("/b.ogg", "/live.ogg")
+	g.SetFallback("/x.ogg", "/live.ogg")
+	g.SetFallback("/c.ogg", "/d.ogg")
+
+	c.Assert(errors.Is(g.Validate(), ErrFallbackCycle), Equals, true)
+	c.Assert(errors.Is(g.Validate(), ErrFallbackDangling), Equals, true)
+	c.Assert(g.Validate(), ErrorMatches, "Fallback chain contains a loop: /autodj.ogg → /b.ogg → /live.ogg → /autodj.ogg\nFallback points to unknown mount: /c.ogg → /d.ogg")
+	c.Assert(g.Chain("/x.ogg"), DeepEquals, []string{"/live.ogg", "/autodj.ogg", "/b.ogg", "/live.ogg"})
+
+	var ng *FallbackGraph
+
+	ng.AddConfig(config)
+	ng.AddMounts(nil)
+	ng.AddStats(nil)
+	ng.SetFallback("/a", "/b")
+
+	c.Assert(ng.Node("/a"), IsNil)
+	c.Assert(ng.Mounts(), IsNil)
+	c.Assert(ng.Chain("/a"), IsNil)
+	c.Assert(ng.Validate(), Equals, ErrNilGraph)
+	c.Assert(ng.Check("/a", "/b"), Equals, ErrNilGraph)
+
+	c.Assert(isCompatibleContentType("audio/ogg", "application/ogg; codecs=vorbis"), Equals, true)
+	c.Assert(isCompatibleContentType("audio/aacp", "audio/aac"), Equals, true)
+	c.Assert(isCompatibleContentType("audio/mp3", "audio/mpeg"), Equals, true)
+	c.Assert(isCompatibleContentType("audio/mpeg", ""), Equals, true)
+	c.Assert(isCompatibleContentType("audio/mpeg", "audio/aac"), Equals, false)
+}
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("fallback.xml"))
+func (s *IcecastSuite) TestUpdateFallbackSafe(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/listmounts":
+			w.Write(getResponseData("listmounts.xml"))
+		case "/admin/fallback":
+			handlerFallback(w, r)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+
+	defer server.Close()
+
+	api, _ := NewAPI(server.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	g := NewFallbackGraph()
+	g.SetFallback("/source2.ogg", "/source1.ogg")
+
+	c.Assert(api.UpdateFallbackSafe(nil, "/source1.ogg", "/source2.ogg"), Equals, ErrNilGraph)
+	c.Assert(api.UpdateFallbackSafe(g, "/source1.ogg", "/source2.ogg"), ErrorMatches, "Fallback chain contains a loop: .*")
+	c.Assert(api.UpdateFallbackSafe(g, "/source1.ogg", "/source3.ogg"), ErrorMatches, "Fallback points to unknown mount: .*")
+
+	g.SetFallback("/source2.ogg", "")
+
+	c.Assert(api.UpdateFallbackSafe(g, "/source1.ogg", "/source2.ogg"), IsNil)
+	c.Assert(g.Node("/source1.ogg").Live, Equals, true)
+	c.Assert(g.Chain("/source1.ogg"), DeepEquals, []string{"/source2.ogg"})
+
+	c.Assert(api.UpdateFallbackSafe(g, "/source2.ogg", "/source1.ogg"), NotNil)
+
+	g.SetFallback("/source1.ogg", "")
+
+	c.Assert(api.UpdateFallbackSafe(g, "/source2.ogg", "/source1.ogg"), NotNil)
+
+	api, _ = NewAPI("http://127.0.0.1:40000", "john", "pass")
+
+	c.Assert(api.UpdateFallbackSafe(g, "/source1.ogg", "/source2.ogg"), NotNil)
 }
 
-func handlerListClients(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
-	}
+func (s *IcecastSuite) TestYPClient(c *C) {
+	var actions []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		action := r.PostForm.Get("action")
+		actions = append(actions, action+":"+r.PostForm.Get("st"))
+
+		switch {
+		case action == YP_ACTION_ADD && r.PostForm.Get("listenurl") == "http://localhost:8000/bad.ogg":
+			w.Header().Set("YPResponse", "0")
+			w.Header().Set("YPMessage", "Bad stream")
+		case action == YP_ACTION_ADD && r.PostForm.Get("listenurl") == "http://localhost:8000/nosid.ogg":
+			w.Header().Set("YPResponse", "1")
+		case action == YP_ACTION_ADD:
+			w.Header().Set("YPResponse", "1")
+			w.Header().Set("SID", "sid-"+r.PostForm.Get("sn"))
+			w.Header().Set("TouchFreq", "60")
+		case r.PostForm.Get("sid") == "sid-bad":
+			w.Header().Set("YPResponse", "0")
+		case r.PostForm.Get("sid") == "sid-down":
+			w.WriteHeader(503)
+		case r.PostForm.Get("sid") != "":
+			w.Header().Set("YPResponse", "1")
+		default:
+			w.WriteHeader(400)
+		}
+	}))
+
+	defer server.Close()
+
+	_, err := NewYPClient("")
+	c.Assert(err, Equals, ErrEmptyURL)
+
+	yp, err := NewYPClient(server.URL)
 
-	mount := r.URL.Query().Get("mount")
+	c.Assert(err, IsNil)
 
-	if mount != "/source1.ogg" {
-		w.WriteHeader(400)
-		return
+	yp.SetUserAgent("go-icecast-tester", "1.0.0")
+	yp.SetPassword("test")
+
+	source := &Source{
+		ListenURL: "http://localhost:8000/live.ogg",
+		Public:    true,
+		Info:      &SourceInfo{Name: "live", Type: "application/ogg"},
+		AudioInfo: &AudioInfo{RawInfo: "ice-channels=2"},
+		Track:     &TrackInfo{RawInfo: "Artist - Song"},
+		Stats:     &SourceStats{Listeners: 10},
 	}
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("listclients.xml"))
+	c.Assert(yp.Add("/live.ogg", nil), Equals, ErrYPNoSource)
+	c.Assert(yp.Add("/live.ogg", &Source{}), Equals, ErrYPNoListenURL)
+	c.Assert(yp.Add("/bad.ogg", &Source{ListenURL: "http://localhost:8000/bad.ogg"}), ErrorMatches, `YP server rejected "add" action: Bad stream`)
+	c.Assert(yp.Add("/nosid.ogg", &Source{ListenURL: "http://localhost:8000/nosid.ogg"}), ErrorMatches, "YP server didn't return SID for mount /nosid.ogg")
+	c.Assert(yp.Touch("/live.ogg", nil), Equals, ErrYPNoSource)
+	c.Assert(yp.Touch("/live.ogg", source), Equals, ErrYPNotListed)
+	c.Assert(yp.Remove("/live.ogg"), Equals, ErrYPNotListed)
+	c.Assert(yp.Sync(nil), NotNil)
+
+	stats := &Stats{Sources: Sources{"/live.ogg": source}}
+
+	c.Assert(yp.Sync(stats), IsNil)
+	c.Assert(yp.Entry("live.ogg").SID, Equals, "sid-live")
+	c.Assert(yp.Entry("/live.ogg").TouchFreq, Equals, time.Minute)
+
+	c.Assert(yp.Sync(stats), IsNil)
+	c.Assert(yp.Entry("/live.ogg").Song, Equals, "Artist - Song")
+	c.Assert(yp.Sync(stats), IsNil)
+
+	source.Track.RawInfo = "Artist - Song 2"
+
+	c.Assert(yp.Sync(stats), IsNil)
+
+	source.Public = false
+
+	c.Assert(yp.Sync(stats), IsNil)
+	c.Assert(yp.Entry("/live.ogg"), IsNil)
+
+	source.Public = true
+
+	c.Assert(yp.Sync(stats), IsNil)
+	c.Assert(yp.Sync(&Stats{}), IsNil)
+	c.Assert(yp.Entry("/live.ogg"), IsNil)
+
+	c.Assert(actions, DeepEquals, []string{
+		"add:", "add:", "add:", "touch:Artist - Song",
+		"touch:Artist - Song 2", "remove:", "add:", "remove:",
+	})
+
+	yp.entries["/bad.ogg"] = &YPEntry{Mount: "/bad.ogg", SID: "sid-bad"}
+
+	c.Assert(yp.Touch("/bad.ogg", source), ErrorMatches, `YP server rejected "touch" action: unknown error`)
+	c.Assert(yp.Entry("/bad.ogg"), IsNil)
+
+	yp.entries["/down.ogg"] = &YPEntry{Mount: "/down.ogg", SID: "sid-down"}
+
+	c.Assert(yp.Touch("/down.ogg", source), ErrorMatches, "YP server returned non-ok status code 503")
+	c.Assert(yp.Entry("/down.ogg"), NotNil)
+	c.Assert(yp.Entry("/down.ogg").SID, Equals, "sid-down")
+
+	delete(yp.entries, "/down.ogg")
+	yp.entries["/bad.ogg"] = &YPEntry{Mount: "/bad.ogg"}
+
+	c.Assert(yp.Sync(&Stats{}), ErrorMatches, "/bad.ogg: YP server returned non-ok status code 400")
+
+	yp, _ = NewYPClient("http://127.0.0.1:40000")
+
+	c.Assert(yp.Add("/live.ogg", source), ErrorMatches, "Can't send request to YP server: .*")
 }
 
-func handlerMoveClients(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
-	}
+func (s *IcecastSuite) TestAuthHandler(c *C) {
+	var lastReq *AuthRequest
+
+	handler := NewAuthHandler(AuthenticatorFunc(
+		func(ctx context.Context, r *AuthRequest) (AuthResult, error) {
+			lastReq = r
+
+			switch {
+			case r.Action == AUTH_LISTENER_REMOVE, r.Action == AUTH_MOUNT_ADD:
+				return AuthResult{Allow: true}, nil
+			case r.Action == AUTH_STREAM_AUTH && r.Admin:
+				return AuthResult{}, errors.New("Admin access is not allowed")
+			case r.User == "john" && r.Password == "test":
+				return AuthResult{Allow: true, TimeLimit: time.Hour}, nil
+			}
+
+			return AuthResult{Message: "Invalid credentials"}, nil
+		},
+	))
+
+	w := sendAuthRequest(handler, url.Values{
+		"action": {AUTH_LISTENER_ADD}, "server": {"localhost"}, "port": {"8000"},
+		"client": {"15"}, "mount": {"/live.mp3?token=abcd"}, "user": {"john"},
+		"pass": {"test"}, "ip": {"192.168.1.1"}, "agent": {"VLC"},
+	})
+
+	c.Assert(w.Code, Equals, 200)
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "1")
+	c.Assert(w.Header().Get("icecast-auth-timelimit"), Equals, "3600")
+	c.Assert(lastReq.Action, Equals, AUTH_LISTENER_ADD)
+	c.Assert(lastReq.Server, Equals, "localhost")
+	c.Assert(lastReq.Port, Equals, 8000)
+	c.Assert(lastReq.ClientID, Equals, 15)
+	c.Assert(lastReq.Mount, Equals, "/live.mp3")
+	c.Assert(lastReq.Query.Get("token"), Equals, "abcd")
+	c.Assert(lastReq.IP, Equals, "192.168.1.1")
+	c.Assert(lastReq.UserAgent, Equals, "VLC")
+
+	w = sendAuthRequest(handler, url.Values{
+		"action": {AUTH_LISTENER_ADD}, "mount": {"/live.mp3"}, "user": {"john"},
+	})
+
+	c.Assert(w.Code, Equals, 200)
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "")
+	c.Assert(w.Header().Get("icecast-auth-message"), Equals, "Invalid credentials")
+
+	w = sendAuthRequest(handler, url.Values{
+		"action": {AUTH_LISTENER_REMOVE}, "mount": {"/live.mp3"}, "duration": {"120"},
+	})
+
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "1")
+	c.Assert(lastReq.Duration, Equals, 2*time.Minute)
+
+	w = sendAuthRequest(handler, url.Values{"action": {AUTH_MOUNT_ADD}, "mount": {"/live.mp3"}})
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "1")
+
+	w = sendAuthRequest(handler, url.Values{
+		"action": {AUTH_STREAM_AUTH}, "mount": {"/live.mp3"}, "admin": {"1"},
+	})
+
+	c.Assert(w.Code, Equals, 500)
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "")
+	c.Assert(lastReq.Admin, Equals, true)
+
+	w = sendAuthRequest(handler, url.Values{"action": {"unknown"}, "mount": {"/live.mp3"}})
+	c.Assert(w.Code, Equals, 400)
+	w = sendAuthRequest(handler, url.Values{"mount": {"/live.mp3"}})
+	c.Assert(w.Code, Equals, 400)
+	w = sendAuthRequest(handler, url.Values{"action": {AUTH_MOUNT_ADD}})
+	c.Assert(w.Code, Equals, 400)
+
+	r := httptest.NewRequest("PUT", "/auth", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	c.Assert(w.Code, Equals, 405)
+
+	r = httptest.NewRequest("POST", "/auth", strings.NewReader("%%%"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	c.Assert(w.Code, Equals, 400)
+
+	w = sendAuthRequest(&AuthHandler{}, url.Values{"action": {AUTH_MOUNT_ADD}, "mount": {"/live.mp3"}})
+	c.Assert(w.Code, Equals, 500)
+}
 
-	mount := r.URL.Query().Get("mount")
-	destination := r.URL.Query().Get("destination")
+func (s *IcecastSuite) TestTokenSigner(c *C) {
+	_, err := NewTokenSigner("", []byte("key"))
+	c.Assert(err, Equals, ErrEmptyKeyID)
+	_, err = NewTokenSigner("k1", nil)
+	c.Assert(err, Equals, ErrEmptyKey)
 
-	switch {
-	case mount != "/source1.ogg",
-		destination != "/source2.ogg":
-		w.WriteHeader(400)
-		return
-	}
+	signer, err := NewTokenSigner("k1", []byte("secret1"))
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("moveclients.xml"))
+	c.Assert(err, IsNil)
+
+	source := &Source{ListenURL: "http://localhost:8000/live.mp3?type=.mp3"}
+	signed, err := signer.SignSource(source, "user123", time.Hour)
+
+	c.Assert(err, IsNil)
+
+	u, _ := url.Parse(signed)
+
+	c.Assert(u.Path, Equals, "/live.mp3")
+	c.Assert(u.Query().Get("type"), Equals, ".mp3")
+	c.Assert(u.Query().Get("lid"), Equals, "user123")
+	c.Assert(u.Query().Get("kid"), Equals, "k1")
+
+	token, err := signer.Validate("live.mp3", u.Query())
+
+	c.Assert(err, IsNil)
+	c.Assert(token.ListenerID, Equals, "user123")
+	c.Assert(token.Mount, Equals, "/live.mp3")
+	c.Assert(token.KeyID, Equals, "k1")
+
+	_, err = signer.Validate("/other.mp3", u.Query())
+	c.Assert(err, Equals, ErrTokenSignature)
+
+	q := u.Query()
+	q.Set("lid", "user456")
+	_, err = signer.Validate("/live.mp3", q)
+	c.Assert(err, Equals, ErrTokenSignature)
+
+	q = u.Query()
+	q.Set("exp", "abcd")
+	_, err = signer.Validate("/live.mp3", q)
+	c.Assert(err, Equals, ErrTokenMalformed)
+
+	_, err = signer.Validate("/live.mp3", url.Values{})
+	c.Assert(err, Equals, ErrTokenMissing)
+
+	// Key rotation
+	c.Assert(signer.SetCurrentKey("k2"), NotNil)
+	c.Assert(signer.AddKey("k2", []byte("secret2")), IsNil)
+	c.Assert(signer.SetCurrentKey("k2"), IsNil)
+	c.Assert(signer.RemoveKey("k2"), NotNil)
+
+	signed2, _ := signer.Sign("http://localhost:8000/live.mp3", "", time.Now().Add(time.Minute))
+	u2, _ := url.Parse(signed2)
+
+	c.Assert(u2.Query().Get("kid"), Equals, "k2")
+	c.Assert(u2.Query().Has("lid"), Equals, false)
+
+	_, err = signer.Validate("/live.mp3", u2.Query())
+	c.Assert(err, IsNil)
+	_, err = signer.Validate("/live.mp3", u.Query())
+	c.Assert(err, IsNil)
+
+	c.Assert(signer.RemoveKey("k1"), IsNil)
+
+	_, err = signer.Validate("/live.mp3", u.Query())
+	c.Assert(err, Equals, ErrTokenUnknownKey)
+
+	// Expiration and clock skew
+	signed, _ = signer.Sign("http://localhost:8000/live.mp3", "", time.Now().Add(-time.Minute))
+	u, _ = url.Parse(signed)
+
+	_, err = signer.Validate("/live.mp3", u.Query())
+	c.Assert(err, Equals, ErrTokenExpired)
+
+	signer.Skew = 5 * time.Minute
+
+	_, err = signer.Validate("/live.mp3", u.Query())
+	c.Assert(err, IsNil)
+
+	_, err = signer.Sign("http://localhost:8000", "", time.Now())
+	c.Assert(err, NotNil)
+	_, err = signer.Sign("%%%", "", time.Now())
+	c.Assert(err, NotNil)
+	_, err = signer.SignSource(nil, "", time.Minute)
+	c.Assert(err, NotNil)
+
+	// URL authentication
+	handler := NewAuthHandler(signer)
+
+	w := sendAuthRequest(handler, url.Values{
+		"action": {AUTH_LISTENER_ADD}, "mount": {"/live.mp3?" + u2.RawQuery},
+	})
+
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "1")
+	c.Assert(w.Header().Get("icecast-auth-timelimit"), Matches, "3(59|60)")
+
+	w = sendAuthRequest(handler, url.Values{
+		"action": {AUTH_LISTENER_ADD}, "mount": {"/other.mp3?" + u2.RawQuery},
+	})
+
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "")
+	c.Assert(w.Header().Get("icecast-auth-message"), Equals, "Token signature is invalid")
+
+	w = sendAuthRequest(handler, url.Values{
+		"action": {AUTH_LISTENER_REMOVE}, "mount": {"/live.mp3"},
+	})
+
+	c.Assert(w.Header().Get("icecast-auth-user"), Equals, "1")
+
+	// Token which expires in less than second must not grant unlimited listening
+	signer.Skew = 0
+	signed, _ = signer.Sign("http://localhost:8000/live.mp3", "", time.Now().Add(500*time.Millisecond))
+	u, _ = url.Parse(signed)
+
+	result, err := signer.Authenticate(context.Background(), &AuthRequest{
+		Action: AUTH_LISTENER_ADD, Mount: "/live.mp3", Query: u.Query(),
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(result.Allow, Equals, false)
+	c.Assert(result.TimeLimit, Equals, time.Duration(0))
+	c.Assert(result.Message, Equals, "Token is expired")
+
+	// Source authentication must not be approved by listener tokens
+	result, err = signer.Authenticate(context.Background(), &AuthRequest{
+		Action: AUTH_STREAM_AUTH, Mount: "/live.mp3",
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(result.Allow, Equals, false)
+	c.Assert(result.Message, Equals, "Action is not supported by token authentication")
+
+	result, err = signer.Authenticate(context.Background(), &AuthRequest{
+		Action: AUTH_MOUNT_ADD, Mount: "/live.mp3",
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(result.Allow, Equals, true)
+
+	handler = NewAuthHandler(AuthenticatorFunc(
+		func(ctx context.Context, r *AuthRequest) (AuthResult, error) {
+			return AuthResult{Allow: true, TimeLimit: 300 * time.Millisecond}, nil
+		},
+	))
+
+	w = sendAuthRequest(handler, url.Values{"action": {AUTH_LISTENER_ADD}, "mount": {"/live.mp3"}})
+
+	c.Assert(w.Header().Get("icecast-auth-timelimit"), Equals, "1")
 }
 
-func handlerKillClient(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
-	}
+func (s *IcecastSuite) TestAudioInfoParsing(c *C) {
+	v := ParseAudioInfo("ice-samplerate=48000;ice-bitrate=Quality 0; ice-channels = 2;;broken;quality=0.4")
+
+	c.Assert(v, HasLen, 4)
+	c.Assert(v.SampleRate(), Equals, 48000)
+	c.Assert(v.Channels(), Equals, 2)
+	c.Assert(v.Bitrate(), Equals, "Quality 0")
+	c.Assert(v.Quality(), Equals, "0.4")
+	c.Assert(v.Get("ICE-SAMPLERATE"), Equals, "48000")
+	c.Assert(v.Get("ice-quality"), Equals, "0.4")
+	c.Assert(v.Has("channels"), Equals, true)
+	c.Assert(v.Has("unknown"), Equals, false)
+	c.Assert(v.GetInt("bitrate"), Equals, 0)
+
+	var nv AudioInfoValues
+	var ni *AudioInfo
+
+	c.Assert(nv.Get("bitrate"), Equals, "")
+	c.Assert(ni.Values(), HasLen, 0)
+	c.Assert(ParseAudioInfo(""), HasLen, 0)
+}
 
-	mount := r.URL.Query().Get("mount")
-	id := r.URL.Query().Get("id")
+func (s *IcecastSuite) TestCodecDetection(c *C) {
+	c.Assert(CodecFromID(2), Equals, CODEC_MP3)
+	c.Assert(CodecFromID(14), Equals, CODEC_MP3)
+	c.Assert(CodecFromID(10), Equals, CODEC_AAC)
+	c.Assert(CodecFromID(0), Equals, CODEC_UNKNOWN)
+
+	c.Assert(CodecFromType("application/ogg", "Vorbis"), Equals, CODEC_VORBIS)
+	c.Assert(CodecFromType("application/ogg", "Opus"), Equals, CODEC_OPUS)
+	c.Assert(CodecFromType("audio/ogg", "FLAC"), Equals, CODEC_FLAC)
+	c.Assert(CodecFromType("audio/mpeg", ""), Equals, CODEC_MP3)
+	c.Assert(CodecFromType("audio/aac", ""), Equals, CODEC_AAC)
+	c.Assert(CodecFromType("audio/aacp", ""), Equals, CODEC_HEAAC)
+	c.Assert(CodecFromType("audio/opus", ""), Equals, CODEC_OPUS)
+	c.Assert(CodecFromType("audio/vorbis", ""), Equals, CODEC_VORBIS)
+	c.Assert(CodecFromType("audio/flac; rate=44100", ""), Equals, CODEC_FLAC)
+	c.Assert(CodecFromType("video/webm", ""), Equals, CODEC_UNKNOWN)
+
+	c.Assert(detectCodec(&iceSource{AudioCodecID: 10, ServerType: "audio/aacp"}), Equals, CODEC_HEAAC)
+	c.Assert(detectCodec(&iceSource{AudioCodecID: 10, ServerType: "audio/aac"}), Equals, CODEC_AAC)
+	c.Assert(detectCodec(&iceSource{AudioCodecID: 10}), Equals, CODEC_AAC)
+	c.Assert(detectCodec(&iceSource{AudioCodecID: 2, ServerType: "audio/aacp"}), Equals, CODEC_MP3)
+	c.Assert(detectCodec(&iceSource{ServerType: "audio/aacp"}), Equals, CODEC_HEAAC)
+
+	c.Assert(CODEC_MP3.String(), Equals, "MP3")
+	c.Assert(CODEC_AAC.String(), Equals, "AAC")
+	c.Assert(CODEC_HEAAC.String(), Equals, "HE-AAC")
+	c.Assert(CODEC_VORBIS.String(), Equals, "Vorbis")
+	c.Assert(CODEC_OPUS.String(), Equals, "Opus")
+	c.Assert(CODEC_FLAC.String(), Equals, "FLAC")
+	c.Assert(CODEC_UNKNOWN.String(), Equals, "Unknown")
+
+	var ns *Source
+
+	c.Assert(ns.Codec(), Equals, CODEC_UNKNOWN)
+	c.Assert((&Source{}).Codec(), Equals, CODEC_UNKNOWN)
+	c.Assert((&Source{Info: &SourceInfo{Type: "audio/mpeg"}}).Codec(), Equals, CODEC_MP3)
+}
 
-	switch {
-	case mount != "/source1.ogg",
-		id != "100":
-		w.WriteHeader(400)
-		return
+func (s *IcecastSuite) TestBitrate(c *C) {
+	b := ParseBitrate("128", CODEC_MP3)
+
+	c.Assert(b.Kind, Equals, BITRATE_CONSTANT)
+	c.Assert(b.BPS(), Equals, 128000)
+	c.Assert(b.Kbps(), Equals, 128)
+	c.Assert(b.IsKnown(), Equals, true)
+	c.Assert(b.IsVBR(), Equals, false)
+	c.Assert(b.String(), Equals, "128")
+
+	c.Assert(ParseBitrate("96kbps", CODEC_AAC).BPS(), Equals, 96000)
+	c.Assert(ParseBitrate("64k", CODEC_AAC).BPS(), Equals, 64000)
+	c.Assert(ParseBitrate("320 kbit/s", CODEC_MP3).BPS(), Equals, 320000)
+	c.Assert(ParseBitrate("128000bps", CODEC_MP3).BPS(), Equals, 128000)
+	c.Assert(ParseBitrate("128000", CODEC_MP3).Kbps(), Equals, 128)
+	c.Assert(ParseBitrate("10000", CODEC_MP3).Kbps(), Equals, 10000)
+	c.Assert(ParseBitrate("10001", CODEC_MP3).BPS(), Equals, 10001)
+	c.Assert(ParseBitrate("0", CODEC_MP3).IsKnown(), Equals, false)
+	c.Assert(ParseBitrate("", CODEC_MP3).IsKnown(), Equals, false)
+	c.Assert(ParseBitrate("abcd", CODEC_MP3).IsKnown(), Equals, false)
+	c.Assert(ParseBitrate("Quality high", CODEC_VORBIS).IsKnown(), Equals, false)
+
+	b = ParseBitrate("Quality 0", CODEC_VORBIS)
+
+	c.Assert(b.Kind, Equals, BITRATE_QUALITY)
+	c.Assert(b.IsVBR(), Equals, true)
+	c.Assert(b.Quality, Equals, 0.0)
+	c.Assert(b.BPS(), Equals, 64000)
+	c.Assert(b.String(), Equals, "Quality 0")
+
+	c.Assert(ParseBitrate("q5", CODEC_VORBIS).Kbps(), Equals, 160)
+	c.Assert(ParseBitrate("Quality 4.5", CODEC_VORBIS).Kbps(), Equals, 144)
+	c.Assert(ParseBitrate("Quality -1", CODEC_VORBIS).Kbps(), Equals, 45)
+	c.Assert(ParseBitrate("Quality 10", CODEC_VORBIS).Kbps(), Equals, 500)
+	c.Assert(ParseBitrate("Quality 15", CODEC_VORBIS).Kbps(), Equals, 500)
+	c.Assert(ParseBitrate("Quality 6", CODEC_OPUS).Kbps(), Equals, 128)
+	c.Assert(ParseBitrate("Quality 6", CODEC_MP3).Kbps(), Equals, 0)
+	c.Assert(ParseBitrate("Quality 2.5", CODEC_UNKNOWN).String(), Equals, "Quality 2.5")
+
+	c.Assert(NewBitrate(-1).IsKnown(), Equals, false)
+	c.Assert(Bitrate{}.String(), Equals, "")
+}
+
+func (s *IcecastSuite) TestHealthEvaluator(c *C) {
+	now := time.Now()
+	e := NewHealthEvaluator()
+
+	source := &Source{
+		MetadataUpdated: now.Add(-time.Minute),
+		AudioInfo:       &AudioInfo{Bitrate: NewBitrate(128000)},
+		Stats: &SourceStats{
+			IncomingBitrate: NewBitrate(128000),
+			TotalBytesRead:  1000,
+			QueueSize:       100,
+		},
 	}
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("killclient.xml"))
-}
+	stats := &Stats{Sources: Sources{"/live.mp3": source, "/empty.mp3": &Source{}}}
 
-func handlerKillSource(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
+	reports := e.evaluate(stats, now)
+
+	c.Assert(reports, HasLen, 2)
+	c.Assert(reports[0].Mount, Equals, "/empty.mp3")
+	c.Assert(reports[0].IsOK(), Equals, true)
+	c.Assert(reports[1].Mount, Equals, "/live.mp3")
+	c.Assert(reports[1].IsOK(), Equals, true)
+	c.Assert(reports[1].State.String(), Equals, "OK")
+
+	// Low bitrate
+	source.Stats.IncomingBitrate = NewBitrate(96000)
+	source.Stats.TotalBytesRead = 2000
+	report := e.evaluate(stats, now)[1]
+
+	c.Assert(report.State, Equals, HEALTH_WARNING)
+	c.Assert(report.Issues[0].Check, Equals, HEALTH_CHECK_BITRATE)
+	c.Assert(report.Reasons(), DeepEquals, []string{"Incoming bitrate 96 kbps is 75% of advertised 128 kbps"})
+
+	source.Stats.IncomingBitrate = NewBitrate(32000)
+	source.Stats.TotalBytesRead = 3000
+	report = e.evaluate(stats, now)[1]
+
+	c.Assert(report.State, Equals, HEALTH_CRITICAL)
+	c.Assert(report.State.String(), Equals, "Critical")
+
+	// Growing queue and slow listeners
+	source.Stats.IncomingBitrate = NewBitrate(128000)
+
+	for i := 1; i <= 3; i++ {
+		source.Stats.QueueSize += 100
+		source.Stats.SlowListeners++
+		source.Stats.TotalBytesRead += 1000
+		report = e.evaluate(stats, now)[1]
 	}
 
-	mount := r.URL.Query().Get("mount")
+	c.Assert(report.State, Equals, HEALTH_WARNING)
+	c.Assert(report.Issues, HasLen, 2)
+	c.Assert(report.Issues[0].Check, Equals, HEALTH_CHECK_QUEUE)
+	c.Assert(report.Issues[1].Check, Equals, HEALTH_CHECK_SLOW_LISTENERS)
 
-	if mount != "/source1.ogg" {
-		w.WriteHeader(400)
-		return
+	for i := 1; i <= 3; i++ {
+		source.Stats.QueueSize += 100
+		source.Stats.TotalBytesRead += 1000
+		report = e.evaluate(stats, now)[1]
 	}
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("killsource.xml"))
+	c.Assert(report.State, Equals, HEALTH_CRITICAL)
+	c.Assert(report.Issues, HasLen, 1)
+	c.Assert(report.Issues[0].Message, Equals, "Queue size is growing for 6 snapshots (700 bytes)")
+
+	// Stalled source and stale metadata
+	source.MetadataUpdated = now.Add(-2 * time.Hour)
+	e.evaluate(stats, now)
+	report = e.evaluate(stats, now)[1]
+
+	c.Assert(report.State, Equals, HEALTH_CRITICAL)
+	c.Assert(report.Issues, HasLen, 2)
+	c.Assert(report.Issues[0].State, Equals, HEALTH_WARNING)
+	c.Assert(report.Issues[0].Message, Equals, "Metadata was not updated for 2h0m0s")
+	c.Assert(report.Issues[1].Check, Equals, HEALTH_CHECK_STALLED)
+
+	e.Thresholds.MetadataStaleCritical = time.Hour
+	c.Assert(e.evaluate(stats, now)[1].Issues[0].State, Equals, HEALTH_CRITICAL)
+
+	// History cleanup
+	e.evaluate(&Stats{}, now)
+	c.Assert(e.history, HasLen, 0)
+
+	source.Stats.TotalBytesRead = 0
+	e.Reset()
+	c.Assert(e.evaluate(stats, now)[1].Issues, HasLen, 1)
+
+	c.Assert(e.Evaluate(nil), IsNil)
+	c.Assert((&HealthEvaluator{}).Evaluate(stats), HasLen, 2)
+
+	// Unknown incoming bitrate
+	e.Reset()
+	report = e.evaluateSource("/dnas", &Source{
+		AudioInfo: &AudioInfo{Bitrate: NewBitrate(128000)},
+		Stats:     &SourceStats{},
+	}, now)
+	c.Assert(report.IsOK(), Equals, true)
+
+	var nr *HealthReport
+
+	c.Assert(nr.IsOK(), Equals, false)
+	c.Assert(nr.Reasons(), IsNil)
+	c.Assert(HEALTH_WARNING.String(), Equals, "Warning")
+	c.Assert(HealthState(10).String(), Equals, "Unknown")
 }
 
-func handlerStats(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
-	}
+func (s *IcecastSuite) TestFailoverController(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
 
-	if statsError {
-		w.WriteHeader(400)
-		return
-	}
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
 
-	statsError = true
+	_, err := NewFailoverController(nil)
+	c.Assert(err, Equals, ErrNilAPI)
+	_, err = NewFailoverController(api)
+	c.Assert(err, Equals, ErrNoPairs)
+	_, err = NewFailoverController(api, FailoverPair{Primary: "/studio.mp3"})
+	c.Assert(err, NotNil)
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("stats.xml"))
-}
+	ctrl, err := NewFailoverController(api, FailoverPair{"studio.mp3", "/backup.mp3"})
 
-func handlerListMounts(w http.ResponseWriter, r *http.Request) {
-	if !isBasicAuthSet(r) {
-		w.WriteHeader(403)
-		return
-	}
+	c.Assert(err, IsNil)
 
-	if mountError {
-		w.WriteHeader(400)
-		return
+	var notified int
+
+	now := time.Now()
+	ctrl.now = func() time.Time { return now }
+	ctrl.OnEvent = func(e FailoverEvent) {
+		// Callback can access controller state without deadlock
+		ctrl.Events()
+		ctrl.IsFailedOver(e.Primary)
+		notified++
 	}
 
-	mountError = true
+	srv.AddSource("/studio.mp3", 10)
+	srv.AddSource("/backup.mp3", 2)
 
-	w.WriteHeader(200)
-	w.Write(getResponseData("listmounts.xml"))
-}
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, false)
 
-func handlerGarbageResponse(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(200)
-	w.Write([]byte("@@@@"))
-}
+	// Primary stalls (no new data)
+	srv.SetStalled("/studio.mp3", true)
 
-// ////////////////////////////////////////////////////////////////////////////////// //
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, false)
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, true)
+	c.Assert(srv.Calls(), DeepEquals, []string{"moveclients:/studio.mp3>/backup.mp3"})
 
-func isBasicAuthSet(r *http.Request) bool {
+	// Primary is back, but not stable yet
+	srv.SetStalled("/studio.mp3", false)
+
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, true)
+
+	now = now.Add(30 * time.Second)
+	srv.SetStalled("/studio.mp3", true)
+	ctrl.Check()
+	ctrl.Check()
+	srv.SetStalled("/studio.mp3", false)
+	ctrl.Check()
+
+	now = now.Add(45 * time.Second)
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, true)
+
+	now = now.Add(30 * time.Second)
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, false)
+	c.Assert(srv.Calls(), DeepEquals, []string{
+		"moveclients:/studio.mp3>/backup.mp3",
+		"moveclients:/backup.mp3>/studio.mp3",
+	})
+
+	// Primary disappears
+	srv.RemoveSource("/studio.mp3")
+	ctrl.Check()
+	ctrl.Check()
+
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, true)
+	c.Assert(srv.Calls(), HasLen, 2)
+
+	srv.AddSource("/studio.mp3", 0)
+	srv.RemoveSource("/backup.mp3")
+	ctrl.Check()
+	now = now.Add(2 * time.Minute)
+	ctrl.Check()
+
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, false)
+	c.Assert(ctrl.IsFailedOver("/unknown.mp3"), Equals, false)
+
+	// Backup is missing
+	srv.RemoveSource("/studio.mp3")
+	ctrl.Check()
+	ctrl.Check()
+
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, false)
+
+	events := ctrl.Events()
+	var types []string
+
+	for _, e := range events {
+		types = append(types, e.Type.String())
+	}
+
+	c.Assert(types, DeepEquals, []string{
+		"primary-down", "switched", "primary-up", "primary-up", "restored",
+		"primary-down", "switched", "primary-up", "restored", "primary-down", "error",
+	})
+	c.Assert(notified, Equals, len(events))
+	c.Assert(events[1].Primary, Equals, "/studio.mp3")
+	c.Assert(events[1].Backup, Equals, "/backup.mp3")
+	c.Assert(FailoverEventType(0).String(), Equals, "unknown")
+
+	// Errors
+	srv.AddSource("/studio.mp3", 1)
+	srv.AddSource("/backup.mp3", 1)
+	srv.SetFailing("moveclients", true)
+	ctrl.Health = nil
+	ctrl.FailAfter = 1
+	ctrl.Process(nil)
+	ctrl.Process(&Stats{Sources: Sources{"/studio.mp3": nil, "/backup.mp3": &Source{}}})
+
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, true)
+
+	ctrl.Check()
+	now = now.Add(2 * time.Minute)
+	ctrl.Check()
+
+	c.Assert(ctrl.IsFailedOver("/studio.mp3"), Equals, true)
+
+	events = ctrl.Events()
+
+	c.Assert(events[len(events)-1].Type, Equals, FAILOVER_EVENT_ERROR)
+	c.Assert(events[len(events)-1].Err, NotNil)
+
+	srv.SetFailing("stats", true)
+	c.Assert(ctrl.Check(), NotNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.Assert(ctrl.Run(ctx, time.Millisecond), Equals, context.Canceled)
+
+	events = ctrl.Events()
+
+	c.Assert(events[len(events)-1].Type, Equals, FAILOVER_EVENT_ERROR)
+	c.Assert(events[len(events)-1].Message, Equals, "Can't fetch stats")
+	c.Assert(events[len(events)-1].Primary, Equals, "")
+	c.Assert(events[len(events)-1].Err, NotNil)
+	c.Assert(notified, Equals, len(events))
+}
+
+func (s *IcecastSuite) TestTakeoverController(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	_, err := NewTakeoverController(nil)
+	c.Assert(err, Equals, ErrNilAPI)
+	_, err = NewTakeoverController(api)
+	c.Assert(err, Equals, ErrNoStations)
+	_, err = NewTakeoverController(api, TakeoverStation{Auto: "/autodj"})
+	c.Assert(err, NotNil)
+
+	ctrl, err := NewTakeoverController(api, TakeoverStation{
+		Auto:             "autodj",
+		Live:             "/live",
+		LiveMeta:         &TrackMeta{Song: "Live now: Morning Show"},
+		MoveNewListeners: true,
+	}, TakeoverStation{
+		Auto:       "/night",
+		Live:       "/night-live",
+		NoFallback: true,
+	})
+
+	c.Assert(err, IsNil)
+
+	var changes []bool
+
+	// Callback can read controller state
+	ctrl.OnChange = func(s TakeoverStation, live bool) {
+		c.Assert(ctrl.IsLive(s.Live), Equals, live)
+		changes = append(changes, live)
+	}
+
+	srv.AddSource("/autodj", 5)
+	srv.UpdateSource("/autodj", func(s *iceSource) {
+		s.YpCurrentlyPlaying = "Artist - Track"
+	})
+
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsLive("/live"), Equals, false)
+
+	srv.AddSource("/live", 0)
+
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsLive("live"), Equals, true)
+	c.Assert(ctrl.IsLive("/unknown"), Equals, false)
+
+	srv.AddSource("/autodj", 2)
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.Check(), IsNil)
+
+	srv.RemoveSource("/live")
+
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsLive("/live"), Equals, false)
+	c.Assert(ctrl.Check(), IsNil)
+
+	c.Assert(srv.Calls(), DeepEquals, []string{
+		"fallback:/live",
+		"moveclients:/autodj>/live",
+		"metadata:/autodj:Live now: Morning Show",
+		"moveclients:/autodj>/live",
+		"metadata:/autodj:Artist - Track",
+	})
+	c.Assert(changes, DeepEquals, []bool{true, false})
+
+	srv.AddSource("/night", 3)
+	srv.AddSource("/night-live", 0)
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsLive("/night-live"), Equals, true)
+	srv.RemoveSource("/night")
+	srv.RemoveSource("/night-live")
+	c.Assert(ctrl.Check(), IsNil)
+	c.Assert(ctrl.IsLive("/night-live"), Equals, false)
+	c.Assert(srv.Calls()[5:], DeepEquals, []string{
+		"moveclients:/night>/night-live",
+	})
+
+	// Errors
+	c.Assert(ctrl.Process(nil), IsNil)
+
+	srv.AddSource("/live", 0)
+	srv.SetFailing("fallback", true)
+	c.Assert(ctrl.Check(), ErrorMatches, "/live: Can't set fallback: .*")
+	srv.SetFailing("fallback", false)
+
+	srv.SetFailing("moveclients", true)
+	c.Assert(ctrl.Check(), ErrorMatches, "/live: Can't move listeners: .*")
+	srv.SetFailing("moveclients", false)
+
+	srv.SetFailing("metadata", true)
+	c.Assert(ctrl.Check(), ErrorMatches, "/live: Can't update metadata: .*")
+	c.Assert(ctrl.IsLive("/live"), Equals, false)
+	srv.SetFailing("metadata", false)
+	c.Assert(ctrl.Check(), IsNil)
+
+	srv.RemoveSource("/live")
+	srv.SetFailing("metadata", true)
+	c.Assert(ctrl.Check(), ErrorMatches, "/live: Can't restore metadata: .*")
+	c.Assert(ctrl.IsLive("/live"), Equals, false)
+	srv.SetFailing("metadata", false)
+
+	srv.SetFailing("stats", true)
+	c.Assert(ctrl.Check(), NotNil)
+
+	var runErrs []error
+
+	ctrl.OnError = func(err error) { runErrs = append(runErrs, err) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.Assert(ctrl.Run(ctx, time.Millisecond), Equals, context.Canceled)
+	c.Assert(runErrs, HasLen, 1)
+
+	c.Assert(getTrackMeta(nil), IsNil)
+	c.Assert(getTrackMeta(&Source{Track: &TrackInfo{}}), IsNil)
+}
+
+func (s *IcecastSuite) TestCluster(c *C) {
+	api, _ := NewAPI("http://127.0.0.1:40000", "john", "pass")
+
+	_, err := NewCluster()
+	c.Assert(err, Equals, ErrNoNodes)
+	_, err = NewCluster(nil)
+	c.Assert(err, ErrorMatches, "Cluster node is nil")
+	_, err = NewCluster(&ClusterNode{})
+	c.Assert(err, ErrorMatches, "Cluster node has no name")
+	_, err = NewCluster(&ClusterNode{Name: "n1"})
+	c.Assert(err, ErrorMatches, `Cluster node "n1" has no API client`)
+	_, err = NewCluster(&ClusterNode{Name: "n1", API: api})
+	c.Assert(err, ErrorMatches, `Cluster node "n1" has no public URL`)
+	_, err = NewCluster(
+		&ClusterNode{Name: "n1", API: api, PublicURL: "http://n1"},
+		&ClusterNode{Name: "n1", API: api, PublicURL: "http://n1"},
+	)
+	c.Assert(err, ErrorMatches, `Cluster node "n1" is defined more than once`)
+
+	cluster, err := NewCluster(&ClusterNode{Name: "n1", API: api, PublicURL: "http://n1"})
+
+	c.Assert(err, IsNil)
+	c.Assert(cluster.Nodes(), HasLen, 1)
+	c.Assert(cluster.Node("n1"), NotNil)
+	c.Assert(cluster.Node("n2"), IsNil)
+}
+
+func (s *IcecastSuite) TestBalancer(c *C) {
+	srv1, srv2 := newFakeIcecast(), newFakeIcecast()
+
+	defer srv1.Close()
+	defer srv2.Close()
+
+	api1, _ := NewAPI(srv1.URL, _DEFAULT_USER, _DEFAULT_PASS)
+	api2, _ := NewAPI(srv2.URL, _DEFAULT_USER, _DEFAULT_PASS)
+	api3, _ := NewAPI("http://127.0.0.1:40000", _DEFAULT_USER, _DEFAULT_PASS)
+
+	cluster, _ := NewCluster(
+		&ClusterNode{Name: "relay1", API: api1, PublicURL: "http://relay1.example.com/"},
+		&ClusterNode{Name: "relay2", API: api2, PublicURL: "http://relay2.example.com"},
+		&ClusterNode{Name: "relay3", API: api3, PublicURL: "http://relay3.example.com"},
+	)
+
+	unlimited := func(s *iceSource) { s.MaxListeners = "unlimited" }
+
+	srv1.AddSource("/live.mp3", 10)
+	srv2.AddSource("/live.mp3", 90)
+
+	b := NewBalancer(cluster)
+
+	node, err := b.Pick("live.mp3", "")
+
+	c.Assert(err, IsNil)
+	c.Assert(node.Name, Equals, "relay1")
+
+	// Unlimited max listeners
+	srv2.AddSource("/live.mp3", 5)
+	srv2.UpdateSource("/live.mp3", unlimited)
+	b.Invalidate()
+
+	node, err = b.Pick("/live.mp3", "")
+
+	c.Assert(err, IsNil)
+	c.Assert(node.Name, Equals, "relay2")
+
+	// Assigned listeners are counted until stats are fetched again
+	srv1.UpdateSource("/live.mp3", unlimited)
+	srv2.AddSource("/live.mp3", 11)
+	srv2.UpdateSource("/live.mp3", unlimited)
+	b.Invalidate()
+
+	var picked []string
+
+	for range 4 {
+		node, _ = b.Pick("/live.mp3", "")
+		picked = append(picked, node.Name)
+	}
+
+	c.Assert(picked, DeepEquals, []string{"relay1", "relay1", "relay2", "relay1"})
+
+	srv1.AddSource("/live.mp3", 100)
+	b.Invalidate()
+
+	node, _ = b.Pick("/live.mp3", "")
+	c.Assert(node.Name, Equals, "relay2")
+
+	// Sticky assignment
+	node, _ = b.Pick("/live.mp3", "10.0.0.1")
+	c.Assert(node.Name, Equals, "relay2")
+
+	srv1.AddSource("/live.mp3", 0)
+	srv2.AddSource("/live.mp3", 50)
+	b.Invalidate()
+
+	node, _ = b.Pick("/live.mp3", "10.0.0.1")
+	c.Assert(node.Name, Equals, "relay2")
+	node, _ = b.Pick("/live.mp3", "10.0.0.2")
+	c.Assert(node.Name, Equals, "relay1")
+
+	// Mixed cluster
+	srv1.AddSource("/mixed.mp3", 500)
+	srv1.UpdateSource("/mixed.mp3", func(s *iceSource) { s.MaxListeners = "1000" })
+	srv2.AddSource("/mixed.mp3", 50)
+
+	mc, _ := NewCluster(
+		&ClusterNode{Name: "relay1", API: api1, PublicURL: "http://relay1.example.com"},
+		&ClusterNode{Name: "relay2", API: api2, PublicURL: "http://relay2.example.com", MaxBitrate: 100_000_000},
+	)
+
+	mb := NewBalancer(mc)
+
+	node, _ = mb.Pick("/mixed.mp3", "")
+	c.Assert(node.Name, Equals, "relay1")
+
+	srv1.UpdateSource("/mixed.mp3", unlimited)
+	mb.Invalidate()
+
+	node, _ = mb.Pick("/mixed.mp3", "")
+	c.Assert(node.Name, Equals, "relay1")
+
+	srv1.AddSource("/mixed.mp3", 995)
+	srv1.UpdateSource("/mixed.mp3", func(s *iceSource) { s.MaxListeners = "1000" })
+	mb.Invalidate()
+
+	node, _ = mb.Pick("/mixed.mp3", "")
+	c.Assert(node.Name, Equals, "relay2")
+
+	// Bandwidth limit
+	srv1.AddSource("/other.mp3", 0)
+	srv1.UpdateSource("/other.mp3", unlimited)
+	srv2.AddSource("/other.mp3", 0)
+	srv2.UpdateSource("/other.mp3", unlimited)
+
+	bc, _ := NewCluster(
+		&ClusterNode{Name: "relay1", API: api1, PublicURL: "http://relay1.example.com", MaxBitrate: 500_000},
+		&ClusterNode{Name: "relay2", API: api2, PublicURL: "http://relay2.example.com", MaxBitrate: 100_000_000},
+	)
+
+	bb := NewBalancer(bc)
+
+	node, _ = bb.Pick("/other.mp3", "")
+	c.Assert(node.Name, Equals, "relay2")
+
+	bb.stats["relay2"].Stats.Stats.OutgoingBitrate = 99_950_000
+
+	for range 3 {
+		node, err = bb.Pick("/other.mp3", "")
+		c.Assert(err, IsNil)
+		c.Assert(node.Name, Equals, "relay1")
+	}
+
+	_, err = bb.Pick("/other.mp3", "")
+	c.Assert(err, ErrorMatches, "No available nodes for mount /other.mp3")
+
+	// Slow nodes
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(500)
+	}))
+
+	defer slow.Close()
+	defer close(release)
+
+	apiSlow, _ := NewAPI(slow.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	sc, _ := NewCluster(
+		&ClusterNode{Name: "slow", API: apiSlow, PublicURL: "http://slow.example.com"},
+		&ClusterNode{Name: "relay1", API: api1, PublicURL: "http://relay1.example.com"},
+	)
+
+	sb := NewBalancer(sc)
+	sb.FetchTimeout = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+
+	for range 2 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			node, err := sb.Pick("/live.mp3", "")
+
+			if c.Check(err, IsNil) {
+				c.Check(node.Name, Equals, "relay1")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	c.Assert(sb.stats["slow"].Err, Equals, ErrNodeTimeout)
+
+	// HTTP handler
+	b.Invalidate()
+	b.TrustForwardedFor = true
+
+	r := httptest.NewRequest("GET", "/live.mp3?token=1", nil)
+	r.Header.Set("X-Forwarded-For", "10.0.0.1, 192.168.0.1")
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, r)
+
+	c.Assert(w.Code, Equals, 302)
+	c.Assert(w.Header().Get("Location"), Equals, "http://relay2.example.com/live.mp3?token=1")
+
+	r = httptest.NewRequest("GET", "/live.mp3", nil)
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, r)
+
+	c.Assert(w.Code, Equals, 302)
+	c.Assert(w.Header().Get("Location"), Equals, "http://relay1.example.com/live.mp3")
+
+	r = httptest.NewRequest("GET", "/unknown.mp3", nil)
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, r)
+
+	c.Assert(w.Code, Equals, 503)
+
+	r = httptest.NewRequest("POST", "/live.mp3", nil)
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, r)
+
+	c.Assert(w.Code, Equals, 405)
+
+	r = httptest.NewRequest("GET", "/live.mp3", nil)
+	r.RemoteAddr = "unix"
+	c.Assert(b.getClientIP(r), Equals, "unix")
+
+	// Expired sticky assignments
+	b.sticky["10.0.0.9"] = &balancerSticky{Node: "relay1", Expires: time.Now().Add(-time.Hour)}
+	b.lastClean = time.Time{}
+	b.Pick("/live.mp3", "10.0.0.3")
+	c.Assert(b.sticky["10.0.0.9"], IsNil)
+
+	_, err = (&Balancer{}).Pick("/live.mp3", "")
+	c.Assert(err, Equals, ErrNoNodes)
+}
+
+func (s *IcecastSuite) TestStatsCache(c *C) {
+	var requests int
+	var mu sync.Mutex
+
+	release := make(chan struct{})
+	failing := false
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		mu.Lock()
+		requests++
+		isFailing := failing
+		mu.Unlock()
+
+		if isFailing {
+			w.WriteHeader(500)
+			return
+		}
+
+		w.WriteHeader(200)
+		w.Write(getResponseData("stats.xml"))
+	}))
+
+	defer srv.Close()
+
+	_, err := NewStatsCache(nil, time.Second)
+	c.Assert(err, Equals, ErrNilAPI)
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+	cache, err := NewStatsCache(api, time.Hour)
+
+	c.Assert(err, IsNil)
+	c.Assert(cache.Age(), Equals, time.Duration(-1))
+
+	// Concurrent requests deduplication
+	var wg sync.WaitGroup
+
+	results := make([]*Stats, 5)
+
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.GetStats()
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	c.Assert(requests, Equals, 1)
+
+	for _, stats := range results {
+		c.Assert(stats, NotNil)
+		c.Assert(stats, Equals, results[0])
+	}
+
+	// Cached data
+	cached, err := cache.Fetch()
+
+	c.Assert(err, IsNil)
+	c.Assert(cached.Stale, Equals, false)
+	c.Assert(cached.Err, IsNil)
+	c.Assert(cached.Stats, Equals, results[0])
+	c.Assert(cached.Age() < time.Second, Equals, true)
+	c.Assert(cache.Age() < time.Second, Equals, true)
+	c.Assert(requests, Equals, 1)
+
+	// Stale data on errors
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+
+	cache.Invalidate()
+	cached, err = cache.Fetch()
+
+	c.Assert(err, IsNil)
+	c.Assert(cached.Stale, Equals, true)
+	c.Assert(cached.Err, NotNil)
+	c.Assert(cached.Stats, Equals, results[0])
+	c.Assert(cached.Age() < time.Second, Equals, true)
+	c.Assert(cache.Age() < time.Second, Equals, true)
+	c.Assert(requests, Equals, 2)
+
+	cache.MaxStale = -1
+	_, err = cache.GetStats()
+	c.Assert(err, NotNil)
+
+	cache.MaxStale = time.Minute
+	cache.TTL = time.Millisecond
+	cache.fetched = time.Now().Add(-time.Hour)
+	_, err = cache.GetStats()
+	c.Assert(err, NotNil)
+
+	cache.fetched = time.Now()
+	_, err = cache.GetStats()
+	c.Assert(err, IsNil)
+
+	// Refresh after error
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+
+	cache.Invalidate()
+	cached, err = cache.Fetch()
+
+	c.Assert(err, IsNil)
+	c.Assert(cached.Stale, Equals, false)
+	c.Assert(cached.Stats, Not(Equals), results[0])
+}
+
+func (s *IcecastSuite) TestStreamDecoding(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/listclients":
+			if r.URL.Query().Get("mount") == "/source1.ogg" {
+				w.Write(getResponseData("listclients.xml"))
+			} else {
+				w.Write([]byte(`<?xml version="1.0"?><iceresponse><message>Source does not exist</message><return>0</return></iceresponse>`))
+			}
+		case "/admin/stats":
+			w.Write(getResponseData("stats.xml"))
+		default:
+			w.Write([]byte(`<icestats><source mount="/broken"><listeners>abc</listeners></source></icestats>`))
+		}
+	}))
+
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	var listeners []*Listener
+
+	for l, err := range api.IterClients("/source1.ogg") {
+		c.Assert(err, IsNil)
+		listeners = append(listeners, l)
+	}
+
+	expected, err := api.ListClients("/source1.ogg")
+
+	c.Assert(err, IsNil)
+	c.Assert(listeners, DeepEquals, expected)
+
+	count := 0
+
+	for range api.IterClients("/source1.ogg") {
+		count++
+		break
+	}
+
+	c.Assert(count, Equals, 1)
+
+	for l, err := range api.IterClients("/unknown.ogg") {
+		c.Assert(l, IsNil)
+		c.Assert(err, ErrorMatches, "Source does not exist")
+	}
+
+	stats, err := api.GetStats()
+	c.Assert(err, IsNil)
+
+	count = 0
+
+	for source, err := range api.IterSources() {
+		c.Assert(err, IsNil)
+		c.Assert(source, DeepEquals, stats.Sources[source.Mount])
+		count++
+	}
+
+	c.Assert(count, Equals, len(stats.Sources))
+
+	var errs []error
+
+	api.streamElements("/broken", nil, "source", func(dec *xml.Decoder, se *xml.StartElement) bool {
+		source := &iceSource{}
+		errs = append(errs, dec.DecodeElement(source, se))
+		return true
+	}, func(err error) { errs = append(errs, err) })
+
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], NotNil)
+
+	api, _ = NewAPI("http://127.0.0.1:40000", _DEFAULT_USER, _DEFAULT_PASS)
+
+	for source, err := range api.IterSources() {
+		c.Assert(source, IsNil)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *IcecastSuite) TestListAllClients(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	for i := range 10 {
+		srv.AddSource(fmt.Sprintf("/mount%d.mp3", i), 0)
+	}
+
+	srv.listeners["/mount1.mp3"] = []*Listener{{ID: 11, IP: "10.0.0.1"}, {ID: 10, IP: "10.0.0.2"}}
+	srv.listeners["/mount5.mp3"] = []*Listener{{ID: 50, IP: "10.0.0.1"}}
+	srv.listeners["/mount9.mp3"] = []*Listener{{ID: 90, IP: "10.0.0.3"}}
+
+	listeners, err := api.ListAllClients(3)
+
+	c.Assert(err, IsNil)
+	c.Assert(listeners, HasLen, 4)
+	c.Assert(listeners[0].Mount, Equals, "/mount1.mp3")
+	c.Assert(listeners[0].ID, Equals, 10)
+	c.Assert(listeners[1].ID, Equals, 11)
+	c.Assert(listeners[3].Mount, Equals, "/mount9.mp3")
+
+	c.Assert(listeners.FindByIP("10.0.0.1"), HasLen, 2)
+	c.Assert(listeners.FindByIP("10.0.0.9"), HasLen, 0)
+	c.Assert(listeners.FindByID(50).Mount, Equals, "/mount5.mp3")
+	c.Assert(listeners.FindByID(50).IP, Equals, "10.0.0.1")
+	c.Assert(listeners.FindByID(1), IsNil)
+	c.Assert(listeners.Mounts(), HasLen, 3)
+	c.Assert(listeners.Mounts()["/mount1.mp3"], HasLen, 2)
+
+	listeners, err = api.ListAllClients(0)
+
+	c.Assert(err, IsNil)
+	c.Assert(listeners, HasLen, 4)
+
+	srv.SetFailing("listclients", true)
+	listeners, err = api.ListAllClients(2)
+
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, `(?s)/mount\d.mp3: API returned non-ok status code 500.*`)
+	c.Assert(listeners, HasLen, 0)
+
+	srv.SetFailing("listmounts", true)
+	_, err = api.ListAllClients(2)
+	c.Assert(err, NotNil)
+}
+
+func (s *IcecastSuite) TestRecorder(c *C) {
+	var mu sync.Mutex
+	var connections int
+
+	title := "Track 1"
+	statsCh := make(chan string, 1000)
+	errCh := make(chan string, 1000)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stalled.mp3" {
+			w.WriteHeader(200)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/admin/listmounts":
+			data, _ := xml.Marshal(&iceMounts{Mounts: []*Mount{
+				{Path: "/live.mp3", ContentType: "audio/mpeg"},
+				{Path: "/stalled.mp3", ContentType: "audio/mpeg"},
+				{Path: "/talk", ContentType: "audio/ogg"},
+			}})
+			w.Write(data)
+
+		case "/admin/stats":
+			data, _ := xml.Marshal(&iceStats{SourcesData: []*iceSource{
+				{Mount: "/live.mp3", Title: title},
+			}})
+			w.Write(data)
+
+			select {
+			case statsCh <- title:
+			default:
+			}
+
+		case "/live.mp3":
+			connections++
+			w.Write(make([]byte, 1000))
+
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+
+	defer srv.Close()
+
+	dir := c.MkDir()
+
+	os.WriteFile(dir+"/live_20200101-000000.mp3", []byte("old"), 0644)
+	os.WriteFile(dir+"/notes.txt", []byte("notes"), 0644)
+	os.Chtimes(dir+"/live_20200101-000000.mp3", time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+	os.Chtimes(dir+"/notes.txt", time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))
+
+	_, err := NewRecorder(nil, dir)
+	c.Assert(err, Equals, ErrNilAPI)
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	_, err = NewRecorder(api, "")
+	c.Assert(err, Equals, ErrEmptyRecordDir)
+
+	rec, err := NewRecorder(api, dir)
+	c.Assert(err, IsNil)
+
+	var errs []string
+
+	rec.Mounts = []string{"live.mp3", "stalled.mp3"}
+	rec.MaxSize = 1500
+	rec.Retention = time.Hour
+	rec.ReconnectDelay = 10 * time.Millisecond
+	rec.ReadTimeout = 50 * time.Millisecond
+	rec.DiscoverInterval = 20 * time.Millisecond
+	rec.MetaInterval = 20 * time.Millisecond
+	rec.OnError = func(mount string, err error) {
+		errCh <- mount + ": " + err.Error()
+	}
+
+	// waitErrors waits until given error is reported given number of times
+	waitErrors := func(msg string, count int) {
+		timeout := time.After(5 * time.Second)
+
+		for countItems(errs, msg) < count {
+			select {
+			case e := <-errCh:
+				errs = append(errs, e)
+			case <-timeout:
+				c.Fatalf("Timeout waiting for error %q", msg)
+			}
+		}
+	}
+
+	// waitStats waits until stats with given title are fetched given number of times
+	waitStats := func(title string, count int) {
+		timeout := time.After(5 * time.Second)
+
+		for count > 0 {
+			select {
+			case t := <-statsCh:
+				if t == title {
+					count--
+				}
+			case <-timeout:
+				c.Fatalf("Timeout waiting for stats with title %q", title)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() { done <- rec.Run(ctx) }()
+
+	waitErrors("/live.mp3: Stream closed by server", 1)
+
+	recording := rec.Recording()
+	slices.Sort(recording)
+	c.Assert(recording, DeepEquals, []string{"/live.mp3", "/stalled.mp3"})
+
+	// Second fetch starts only after track from the first one is written
+	waitStats("Track 1", 2)
+
+	mu.Lock()
+	title = "Track 2"
+	mu.Unlock()
+
+	waitStats("Track 2", 2)
+	waitErrors("/live.mp3: Stream closed by server", 3)
+	waitErrors("/stalled.mp3: No data received from server within 50ms", 1)
+
+	cancel()
+	c.Assert(<-done, Equals, context.Canceled)
+	c.Assert(rec.Recording(), HasLen, 0)
+
+	close(errCh)
+
+	for e := range errCh {
+		errs = append(errs, e)
+	}
+
+	closed := countItems(errs, "/live.mp3: Stream closed by server")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c.Assert(connections >= closed, Equals, true)
+
+	_, err = os.Stat(dir + "/live_20200101-000000.mp3")
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(dir + "/notes.txt")
+	c.Assert(err, IsNil)
+
+	recordings, _ := filepath.Glob(dir + "/live_*.mp3")
+	c.Assert(len(recordings) > 1, Equals, true)
+
+	var total int64
+	var tracks []string
+
+	for _, file := range recordings {
+		info, err := os.Stat(file)
+		c.Assert(err, IsNil)
+		c.Assert(info.Size() <= 1500, Equals, true)
+		total += info.Size()
+
+		data, err := os.ReadFile(file + RECORDING_TRACKS_EXT)
+		c.Assert(err, IsNil)
+
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+
+			t := &RecordingTrack{}
+			c.Assert(json.Unmarshal([]byte(line), t), IsNil)
+			tracks = append(tracks, t.Track.Title)
+		}
+	}
+
+	// Last connection can be interrupted by cancel before data is written
+	c.Assert(total <= int64(connections*1000), Equals, true)
+	c.Assert(total >= int64(closed*1000), Equals, true)
+	c.Assert(tracks, Not(HasLen), 0)
+	c.Assert(slices.Contains(tracks, "Track 1"), Equals, true)
+	c.Assert(slices.Contains(tracks, "Track 2"), Equals, true)
+
+	c.Assert(getRecordingName("/", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)), Equals, "stream_20250102-030405")
+	c.Assert(getRecordingName("/a/b.ogg", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)), Equals, "a_b_20250102-030405")
+	c.Assert(getRecordingExt("/talk", "audio/ogg"), Equals, ".ogg")
+	c.Assert(getRecordingExt("/talk", "audio/aacp"), Equals, ".aac")
+	c.Assert(getRecordingExt("/talk", "audio/flac"), Equals, ".flac")
+	c.Assert(getRecordingExt("/talk", "audio/webm"), Equals, ".webm")
+	c.Assert(getRecordingExt("/talk", "audio/mp3"), Equals, ".mp3")
+	c.Assert(getRecordingExt("/talk", ""), Equals, ".bin")
+	c.Assert(getRecordingExt("/live.MP3", ""), Equals, ".mp3")
+}
+
+func (s *IcecastSuite) TestInterceptors(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 1)
+	srv.AddSource("/backup.mp3", 0)
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	var order []string
+	var calls []APICall
+	var errs []error
+
+	api.Use(
+		func(call *APICall, next APIHandler) error {
+			order = append(order, "first")
+			err := next(call)
+			calls, errs = append(calls, *call), append(errs, err)
+			return err
+		},
+		nil,
+		func(call *APICall, next APIHandler) error {
+			order = append(order, "second")
+
+			switch call.Endpoint {
+			case "/killsource":
+				return errors.New("Read-only mode")
+			case "/killclient":
+				return nil
+			case "/listclients":
+				if call.Query["mount"] == "/skip.mp3" {
+					return nil
+				}
+			case "/fallback":
+				call.Query["fallback"] = "/backup.mp3"
+			}
+
+			return next(call)
+		},
+	)
+
+	c.Assert(api.interceptors, HasLen, 2)
+
+	_, err := api.GetStats()
+	c.Assert(err, IsNil)
+	c.Assert(order, DeepEquals, []string{"first", "second"})
+	c.Assert(calls[0].Endpoint, Equals, "/stats")
+	c.Assert(calls[0].StatusCode, Equals, 200)
+	c.Assert(calls[0].Duration > 0, Equals, true)
+
+	c.Assert(api.KillSource("/live.mp3"), ErrorMatches, "Read-only mode")
+	c.Assert(calls[1].StatusCode, Equals, 0)
+	c.Assert(api.KillClient("/live.mp3", 1), IsNil)
+
+	c.Assert(api.UpdateFallback("/live.mp3", "/other.mp3"), IsNil)
+	c.Assert(calls[3].Query["fallback"], Equals, "/backup.mp3")
+
+	c.Assert(api.MoveClients("/unknown.mp3", "/live.mp3"), ErrorMatches, "Source does not exist")
+	c.Assert(errs[4], ErrorMatches, "Source does not exist")
+	c.Assert(calls[4].StatusCode, Equals, 200)
+
+	c.Assert(srv.Calls(), DeepEquals, []string{"fallback:/live.mp3"})
+
+	for l, err := range api.IterClients("/skip.mp3") {
+		c.Assert(l, IsNil)
+		c.Assert(err, Equals, ErrRequestSkipped)
+	}
+
+	call := &APICall{Query: req.Query{"mount": "/live.mp3", "pass": "secret", "Password": "secret"}}
+
+	c.Assert(call.RedactedQuery(), DeepEquals, req.Query{
+		"mount": "/live.mp3", "pass": REDACTED_VALUE, "Password": REDACTED_VALUE,
+	})
+	c.Assert(call.Query["pass"], Equals, "secret")
+}
+
+func (s *IcecastSuite) TestLogInterceptor(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 0)
+
+	buf := &strings.Builder{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	api.Use(LogInterceptor(logger))
+
+	api.UpdateMeta("/live.mp3", TrackMeta{Song: "Test"})
+	api.KillSource("/unknown.mp3")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	c.Assert(lines, HasLen, 2)
+	c.Assert(lines[0], Matches, `.*level=DEBUG msg="Icecast API request" endpoint=/metadata query=.*status=200 duration=.*`)
+	c.Assert(lines[0], Matches, `.*mode=updinfo.*`)
+	c.Assert(lines[1], Matches, `.*level=ERROR msg="Icecast API request failed" endpoint=/killsource .* error="Source does not exist"`)
+
+	c.Assert(LogInterceptor(nil), NotNil)
+}
+
+func (s *IcecastSuite) TestLatencyHistogram(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+	hist := NewLatencyHistogram()
+
+	api.Use(hist.Interceptor())
+
+	api.GetStats()
+	api.GetStats()
+	api.KillSource("/unknown.mp3")
+
+	stats := hist.Stats()
+
+	c.Assert(stats, HasLen, 2)
+	c.Assert(stats["/stats"].Count, Equals, uint64(2))
+	c.Assert(stats["/stats"].Errors, Equals, uint64(0))
+	c.Assert(stats["/stats"].Mean() > 0, Equals, true)
+	c.Assert(stats["/stats"].Counts, HasLen, len(DefaultLatencyBuckets)+1)
+	c.Assert(stats["/killsource"].Errors, Equals, uint64(1))
+
+	hist = NewLatencyHistogram(10*time.Millisecond, 100*time.Millisecond)
+
+	hist.Add("/stats", 5*time.Millisecond, false)
+	hist.Add("/stats", 10*time.Millisecond, false)
+	hist.Add("/stats", 50*time.Millisecond, false)
+	hist.Add("/stats", time.Second, true)
+
+	stats = hist.Stats()
+
+	c.Assert(stats["/stats"].Counts, DeepEquals, []uint64{2, 1, 1})
+	c.Assert(stats["/stats"].Count, Equals, uint64(4))
+	c.Assert(stats["/stats"].Errors, Equals, uint64(1))
+	c.Assert(stats["/stats"].Sum, Equals, 1065*time.Millisecond)
+
+	stats["/stats"].Counts[0] = 100
+	c.Assert(hist.Stats()["/stats"].Counts[0], Equals, uint64(2))
+
+	hist.Reset()
+
+	c.Assert(hist.Stats(), HasLen, 0)
+	c.Assert(LatencyStats{}.Mean(), Equals, time.Duration(0))
+}
+
+func (s *IcecastSuite) TestDryRun(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 3)
+	srv.AddSource("/backup.mp3", 99)
+	srv.AddSource("/talk.ogg", 0)
+	srv.UpdateSource("/talk.ogg", func(s *iceSource) { s.ServerType = "application/ogg" })
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+	dry := api.DryRun()
+
+	plan, err := dry.KillSource("live.mp3")
+
+	c.Assert(err, IsNil)
+	c.Assert(plan.Action, Equals, PLAN_ACTION_KILL_SOURCE)
+	c.Assert(plan.Mount, Equals, "/live.mp3")
+	c.Assert(plan.AffectedListeners, Equals, 3)
+	c.Assert(plan.String(), Equals, "Source /live.mp3 will be disconnected, 3 listener(s) will be moved to fallback or disconnected")
+
+	_, err = dry.KillSource("/unknown.mp3")
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+	c.Assert(err, ErrorMatches, "Source does not exist: /unknown.mp3")
+
+	plan, err = dry.KillClient("/live.mp3", 2)
+
+	c.Assert(err, IsNil)
+	c.Assert(plan.ClientID, Equals, 2)
+	c.Assert(plan.AffectedListeners, Equals, 1)
+	c.Assert(plan.Description, Equals, "Client 2 (10.0.0.2) will be disconnected from /live.mp3")
+
+	_, err = dry.KillClient("/live.mp3", 100)
+	c.Assert(err, ErrorMatches, "Client does not exist: 100 on /live.mp3")
+	_, err = dry.KillClient("/unknown.mp3", 1)
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+
+	plan, err = dry.MoveClients("/live.mp3", "/backup.mp3")
+
+	c.Assert(err, IsNil)
+	c.Assert(plan.Destination, Equals, "/backup.mp3")
+	c.Assert(plan.AffectedListeners, Equals, 3)
+	c.Assert(plan.Description, Equals, "3 listener(s) will be moved from /live.mp3 to /backup.mp3")
+	c.Assert(plan.Warnings, DeepEquals, []string{"Destination /backup.mp3 has limit of 100 listeners, 2 listener(s) won't fit"})
+
+	plan, err = dry.MoveClients("/talk.ogg", "/live.mp3")
+
+	c.Assert(err, IsNil)
+	c.Assert(plan.Warnings, DeepEquals, []string{
+		"Source /talk.ogg has no listeners",
+		"Sources have different formats (application/ogg → MP3)",
+	})
+
+	_, err = dry.MoveClients("/live.mp3", "live.mp3")
+	c.Assert(err, Equals, ErrSameMount)
+	_, err = dry.MoveClients("/live.mp3", "/unknown.mp3")
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+	_, err = dry.MoveClients("/unknown.mp3", "/live.mp3")
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+
+	plan, err = dry.UpdateFallback("/live.mp3", "/backup.mp3")
+
+	c.Assert(err, IsNil)
+	c.Assert(plan.AffectedListeners, Equals, 0)
+	c.Assert(plan.Warnings, HasLen, 0)
+
+	plan, err = dry.UpdateFallback("/live.mp3", "/autodj.mp3")
+
+	c.Assert(err, IsNil)
+	c.Assert(plan.Warnings, DeepEquals, []string{"Fallback /autodj.mp3 is not active, listeners will be disconnected if source fails"})
+
+	_, err = dry.UpdateFallback("/live.mp3", "/live.mp3")
+	c.Assert(err, Equals, ErrSameMount)
+	_, err = dry.UpdateFallback("/unknown.mp3", "/live.mp3")
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+
+	_, err = dry.KillSource("a b")
+	c.Assert(errors.Is(err, ErrInvalidMount), Equals, true)
+	_, err = dry.KillClient("", 1)
+	c.Assert(err, Equals, ErrEmptyMount)
+	_, err = dry.MoveClients("/live.mp3", "a?b")
+	c.Assert(errors.Is(err, ErrInvalidMount), Equals, true)
+	_, err = dry.MoveClients("a#b", "/live.mp3")
+	c.Assert(errors.Is(err, ErrInvalidMount), Equals, true)
+	_, err = dry.UpdateFallback("/live.mp3", "")
+	c.Assert(err, Equals, ErrEmptyMount)
+	_, err = dry.UpdateFallback("/", "/live.mp3")
+	c.Assert(err, Equals, ErrEmptyMount)
+
+	c.Assert(srv.Calls(), HasLen, 0)
+
+	plan, _ = dry.MoveClients("/live.mp3", "/backup.mp3")
+
+	c.Assert(plan.Apply(), IsNil)
+	c.Assert(srv.Calls(), DeepEquals, []string{"moveclients:/live.mp3>/backup.mp3"})
+
+	plan, _ = dry.UpdateFallback("/live.mp3", "/backup.mp3")
+	c.Assert(plan.Apply(), IsNil)
+	plan, err = dry.KillClient("/backup.mp3", 2)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Apply(), IsNil)
+	plan, err = dry.KillSource("/talk.ogg")
+	c.Assert(err, IsNil)
+	c.Assert(plan.Apply(), IsNil)
+
+	err = (&Plan{Action: PLAN_ACTION_KILL_SOURCE, Mount: "a b", api: api}).Apply()
+	c.Assert(errors.Is(err, ErrInvalidMount), Equals, true)
+	c.Assert((&Plan{Action: "unknown", Mount: "/live.mp3", api: api}).Apply(), ErrorMatches, `Unknown plan action "unknown"`)
+
+	var nilPlan *Plan
+
+	c.Assert(nilPlan.Apply(), Equals, ErrNilPlan)
+	c.Assert(nilPlan.String(), Equals, "")
+
+	srv.SetFailing("stats", true)
+
+	_, err = dry.KillSource("/live.mp3")
+	c.Assert(err, NotNil)
+	_, err = dry.KillClient("/live.mp3", 1)
+	c.Assert(err, NotNil)
+	_, err = dry.MoveClients("/live.mp3", "/backup.mp3")
+	c.Assert(err, NotNil)
+	_, err = dry.UpdateFallback("/live.mp3", "/backup.mp3")
+	c.Assert(err, NotNil)
+
+	srv.SetFailing("stats", false)
+	srv.SetFailing("listclients", true)
+
+	_, err = dry.KillClient("/live.mp3", 1)
+	c.Assert(err, NotNil)
+
+	c.Assert(getCodecName(&Source{}), Equals, "")
+	c.Assert(getCodecName(&Source{Info: &SourceInfo{Type: "audio/mpeg"}}), Equals, "audio/mpeg")
+}
+
+func (s *IcecastSuite) TestReadOnlyClient(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 1)
+	srv.AddSource("/backup.mp3", 0)
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	_, err := NewReadOnlyClient(nil)
+	c.Assert(err, Equals, ErrNilClient)
+
+	ro, err := NewReadOnlyClient(api)
+	c.Assert(err, IsNil)
+
+	stats, err := ro.GetStats()
+	c.Assert(err, IsNil)
+	c.Assert(stats.Sources, HasLen, 2)
+
+	mounts, err := ro.ListMounts()
+	c.Assert(err, IsNil)
+	c.Assert(mounts, HasLen, 2)
+
+	listeners, err := ro.ListClients("/live.mp3")
+	c.Assert(err, IsNil)
+	c.Assert(listeners, HasLen, 1)
+
+	c.Assert(ro.UpdateMeta("/live.mp3", TrackMeta{Song: "Test"}), Equals, ErrReadOnly)
+	c.Assert(ro.MoveClients("/live.mp3", "/backup.mp3"), Equals, ErrReadOnly)
+	c.Assert(ro.KillClient("/live.mp3", 1), Equals, ErrReadOnly)
+	c.Assert(ro.UpdateFallback("/live.mp3", "/backup.mp3"), Equals, ErrReadOnly)
+	c.Assert(ro.KillSource("/live.mp3"), Equals, ErrReadOnly)
+
+	c.Assert(srv.Calls(), HasLen, 0)
+}
+
+func (s *IcecastSuite) TestScopedClient(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 1)
+	srv.AddSource("/live-hq.mp3", 1)
+	srv.AddSource("/backup.mp3", 0)
+	srv.AddSource("/other/news.mp3", 0)
+
+	srv.UpdateSource("/live.mp3", func(s *iceSource) { s.StreamStart = "garbage" })
+	srv.UpdateSource("/other/news.mp3", func(s *iceSource) { s.StreamStart = "garbage" })
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	_, err := NewScopedClient(nil, "/live*")
+	c.Assert(err, Equals, ErrNilClient)
+	_, err = NewScopedClient(api)
+	c.Assert(err, Equals, ErrNoPatterns)
+	_, err = NewScopedClient(api, "/live[")
+	c.Assert(err, ErrorMatches, `Invalid mount pattern "/live\[": syntax error in pattern`)
+
+	sc, err := NewScopedClient(api, "live*", "/backup.mp3")
+	c.Assert(err, IsNil)
+
+	c.Assert(sc.IsAllowed("/live.mp3"), Equals, true)
+	c.Assert(sc.IsAllowed("live-hq.mp3"), Equals, true)
+	c.Assert(sc.IsAllowed("/backup.mp3"), Equals, true)
+	c.Assert(sc.IsAllowed("/other/news.mp3"), Equals, false)
+	c.Assert(sc.IsAllowed("/other/live.mp3"), Equals, false)
+
+	stats, err := sc.GetStats()
+	c.Assert(err, IsNil)
+	c.Assert(stats.Sources, HasLen, 3)
+	c.Assert(stats.Sources["/other/news.mp3"], IsNil)
+	c.Assert(stats.Stats, IsNil)
+	c.Assert(stats.Info, IsNil)
+	c.Assert(stats.Warnings, HasLen, 1)
+	c.Assert(stats.Warnings[0], Matches, "/live.mp3: Can't parse stream_start: .*")
+
+	c.Assert(sc.filterWarnings(&Stats{
+		Sources:  Sources{"/live.mp3": &Source{}, "/other/news.mp3": &Source{}},
+		Warnings: []string{"Can't parse server_start: test", "/live.mp3: test", "/other/news.mp3: test"},
+	}), DeepEquals, []string{"Can't parse server_start: test", "/live.mp3: test"})
+
+	mounts, err := sc.ListMounts()
+	c.Assert(err, IsNil)
+	c.Assert(mounts, HasLen, 3)
+
+	_, err = sc.ListClients("/live.mp3")
+	c.Assert(err, IsNil)
+	_, err = sc.ListClients("/other/news.mp3")
+	c.Assert(errors.Is(err, ErrMountNotAllowed), Equals, true)
+	c.Assert(err, ErrorMatches, "Operation is not allowed for mount /other/news.mp3")
+
+	c.Assert(sc.UpdateMeta("/live.mp3", TrackMeta{Song: "Test"}), IsNil)
+	c.Assert(sc.UpdateMeta("/other/news.mp3", TrackMeta{Song: "Test"}), NotNil)
+	c.Assert(sc.MoveClients("/live.mp3", "/backup.mp3"), IsNil)
+	c.Assert(sc.MoveClients("/live.mp3", "/other/news.mp3"), NotNil)
+	c.Assert(sc.MoveClients("/other/news.mp3", "/live.mp3"), NotNil)
+	c.Assert(sc.UpdateFallback("/live.mp3", "/backup.mp3"), IsNil)
+	c.Assert(sc.UpdateFallback("/live.mp3", "/other/news.mp3"), NotNil)
+	c.Assert(sc.KillClient("/live-hq.mp3", 1), IsNil)
+	c.Assert(sc.KillClient("/other/news.mp3", 1), NotNil)
+	c.Assert(sc.KillSource("/live-hq.mp3"), IsNil)
+	c.Assert(sc.KillSource("/other/news.mp3"), NotNil)
+
+	c.Assert(srv.Calls(), DeepEquals, []string{
+		"metadata:/live.mp3:Test",
+		"moveclients:/live.mp3>/backup.mp3",
+		"fallback:/live.mp3",
+		"killclient:/live-hq.mp3",
+		"killsource:/live-hq.mp3",
+	})
+
+	ro, _ := NewReadOnlyClient(api)
+	sc, _ = NewScopedClient(ro, "/live.mp3")
+
+	c.Assert(sc.KillSource("/live.mp3"), Equals, ErrReadOnly)
+
+	srv.SetFailing("stats", true)
+	srv.SetFailing("listmounts", true)
+
+	_, err = sc.GetStats()
+	c.Assert(err, NotNil)
+	_, err = sc.ListMounts()
+	c.Assert(err, NotNil)
+}
+
+func (s *IcecastSuite) TestMountHandle(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 2)
+	srv.AddSource("/backup.mp3", 0)
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+	m := api.Mount(" live.mp3 ")
+
+	c.Assert(m.Err(), IsNil)
+	c.Assert(m.Path(), Equals, "/live.mp3")
+
+	source, err := m.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(source.Mount, Equals, "/live.mp3")
+	c.Assert(source.Stats.Listeners, Equals, 2)
+
+	listeners, err := m.Clients()
+	c.Assert(err, IsNil)
+	c.Assert(listeners, HasLen, 2)
+
+	c.Assert(m.UpdateMeta(TrackMeta{Song: "Test"}), IsNil)
+	c.Assert(m.SetFallback("backup.mp3"), IsNil)
+	c.Assert(m.KillClient(1), IsNil)
+	c.Assert(m.MoveClientsTo("backup.mp3"), IsNil)
+	c.Assert(m.Kill(), IsNil)
+
+	c.Assert(srv.Calls(), DeepEquals, []string{
+		"metadata:/live.mp3:Test",
+		"fallback:/live.mp3",
+		"killclient:/live.mp3",
+		"moveclients:/live.mp3>/backup.mp3",
+		"killsource:/live.mp3",
+	})
+
+	_, err = api.Mount("/unknown.mp3").Stats()
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+
+	srv.SetFailing("stats", true)
+	_, err = m.Stats()
+	c.Assert(err, NotNil)
+
+	m = api.Mount("/")
+
+	c.Assert(m.Err(), Equals, ErrEmptyMount)
+	c.Assert(m.Path(), Equals, "")
+
+	_, err = m.Stats()
+	c.Assert(err, Equals, ErrEmptyMount)
+	_, err = m.Clients()
+	c.Assert(err, Equals, ErrEmptyMount)
+	c.Assert(m.UpdateMeta(TrackMeta{Song: "Test"}), Equals, ErrEmptyMount)
+	c.Assert(m.SetFallback("/backup.mp3"), Equals, ErrEmptyMount)
+	c.Assert(m.MoveClientsTo("/backup.mp3"), Equals, ErrEmptyMount)
+	c.Assert(m.KillClient(1), Equals, ErrEmptyMount)
+	c.Assert(m.Kill(), Equals, ErrEmptyMount)
+}
+
+func (s *IcecastSuite) TestMountValidation(c *C) {
+	for _, m := range []string{"/live.mp3", "live.mp3", " /live.mp3\t", "/a/b.ogg"} {
+		mount, err := validateMount(m)
+		c.Assert(err, IsNil, Commentf("Mount %q", m))
+		c.Assert(mount, Matches, "/.+", Commentf("Mount %q", m))
+	}
+
+	for _, m := range []string{"", " ", "/"} {
+		_, err := validateMount(m)
+		c.Assert(err, Equals, ErrEmptyMount, Commentf("Mount %q", m))
+	}
+
+	for _, m := range []string{"/live mp3", "/live.mp3?x=1", "/live#1", "/a\\b", "/live\x00"} {
+		_, err := validateMount(m)
+		c.Assert(errors.Is(err, ErrInvalidMount), Equals, true, Commentf("Mount %q", m))
+	}
+
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 1)
+	srv.AddSource("/backup.mp3", 0)
+
+	api, _ := NewAPI(srv.URL, _DEFAULT_USER, _DEFAULT_PASS)
+
+	listeners, err := api.ListClients("live.mp3")
+	c.Assert(err, IsNil)
+	c.Assert(listeners, HasLen, 1)
+
+	_, err = api.ListClients("")
+	c.Assert(err, Equals, ErrEmptyMount)
+	c.Assert(api.UpdateMeta("", TrackMeta{}), Equals, ErrEmptyMount)
+	c.Assert(api.UpdateFallback("", "/backup.mp3"), Equals, ErrEmptyMount)
+	c.Assert(api.UpdateFallback("/live.mp3", ""), Equals, ErrEmptyMount)
+	c.Assert(api.MoveClients("", "/backup.mp3"), Equals, ErrEmptyMount)
+	c.Assert(api.MoveClients("/live.mp3", "/"), Equals, ErrEmptyMount)
+	c.Assert(api.KillClient("", 1), Equals, ErrEmptyMount)
+	c.Assert(api.KillSource("/live mp3"), ErrorMatches, `Mount contains invalid characters: "/live mp3"`)
+
+	for l, err := range api.IterClients("") {
+		c.Assert(l, IsNil)
+		c.Assert(err, Equals, ErrEmptyMount)
+	}
+
+	c.Assert(api.MoveClients("live.mp3", "backup.mp3"), IsNil)
+	c.Assert(srv.Calls(), DeepEquals, []string{"moveclients:/live.mp3>/backup.mp3"})
+}
+
+func (s *IcecastSuite) TestCredentialProviders(c *C) {
+	srv := newFakeIcecast()
+	defer srv.Close()
+
+	srv.AddSource("/live.mp3", 0)
+
+	_, err := NewAPIWithCredentials("", StaticCredentials("a", "b"))
+	c.Assert(err, Equals, ErrEmptyURL)
+	_, err = NewAPIWithCredentials(srv.URL, nil)
+	c.Assert(err, Equals, ErrNilCredentials)
+
+	// Callback
+	calls := 0
+	api, err := NewAPIWithCredentials(srv.URL, CredentialsFunc(func() (Credentials, error) {
+		calls++
+		return Credentials{_DEFAULT_USER, _DEFAULT_PASS}, nil
+	}))
+
+	c.Assert(err, IsNil)
+
+	api.GetStats()
+	api.GetStats()
+
+	c.Assert(calls, Equals, 2)
+
+	c.Assert(api.SetCredentials(nil), Equals, ErrNilCredentials)
+	c.Assert(api.SetCredentials(StaticCredentials(_DEFAULT_USER, "")), IsNil)
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, "Invalid credentials: Password is empty")
+
+	api.SetCredentials(CredentialsFunc(func() (Credentials, error) {
+		return Credentials{}, errors.New("Vault is sealed")
+	}))
+
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, "Can't get credentials: Vault is sealed")
+
+	// Environment variables
+	os.Setenv("TEST_ICECAST_USER", _DEFAULT_USER)
+	os.Setenv("TEST_ICECAST_PASS", _DEFAULT_PASS)
+
+	defer os.Unsetenv("TEST_ICECAST_USER")
+	defer os.Unsetenv("TEST_ICECAST_PASS")
+
+	api.SetCredentials(EnvCredentials("TEST_ICECAST_USER", "TEST_ICECAST_PASS"))
+	_, err = api.GetStats()
+	c.Assert(err, IsNil)
+
+	os.Setenv("TEST_ICECAST_PASS", "")
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, "Can't get credentials: Credentials not found in environment variables TEST_ICECAST_USER and TEST_ICECAST_PASS")
+
+	// File with rotation
+	dir := c.MkDir()
+	file := dir + "/credentials"
+
+	api.SetCredentials(FileCredentials(file))
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, "Can't get credentials: Can't read credentials file: .*")
+
+	os.WriteFile(file, []byte("# Admin\n\n"+_DEFAULT_USER+":"+_DEFAULT_PASS+"\n"), 0600)
+	_, err = api.GetStats()
+	c.Assert(err, IsNil)
+
+	os.WriteFile(file, []byte("admin:wrong-password\n"), 0600)
+	os.Chtimes(file, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, "API returned non-ok status code 403")
+
+	os.WriteFile(file, []byte("admin\n"), 0600)
+	os.Chtimes(file, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second))
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, `Can't get credentials: Can't parse credentials file .*: Credentials must be in "user:password" format`)
+
+	_, err = parseCredentialsFile([]byte("# empty\n"))
+	c.Assert(err, Equals, ErrNoCredentials)
+
+	// Netrc
+	netrc := dir + "/netrc"
+
+	os.WriteFile(netrc, []byte(
+		"machine example.com login user password pass\n"+
+			"machine 127.0.0.1\n  login "+_DEFAULT_USER+"\n  password "+_DEFAULT_PASS+"\n"+
+			"default login anonymous password guest\n"+
+			"macdef init\nmachine 127.0.0.1 login hacker password hacker\n",
+	), 0600)
+
+	api.SetCredentials(NetrcCredentials(netrc, "127.0.0.1"))
+	_, err = api.GetStats()
+	c.Assert(err, IsNil)
+
+	creds, err := NetrcCredentials(netrc, "example.com").Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(creds, DeepEquals, Credentials{"user", "pass"})
+
+	creds, err = NetrcCredentials(netrc, "unknown.com").Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(creds, DeepEquals, Credentials{"anonymous", "guest"})
+
+	_, err = parseNetrc([]byte("machine example.com login user password pass"), "unknown.com")
+	c.Assert(err, ErrorMatches, `Credentials not found for machine "unknown.com"`)
+	_, err = parseNetrc([]byte("machine example.com account test login"), "example.com")
+	c.Assert(err, IsNil)
+	_, err = parseNetrc(nil, "")
+	c.Assert(err, Equals, ErrEmptyNetrcMachine)
+
+	os.Setenv("NETRC", netrc)
+	c.Assert(getNetrcFile(), Equals, netrc)
+	os.Setenv("NETRC", "")
+	c.Assert(getNetrcFile(), Matches, ".*/.netrc")
+
+	// Credentials rotation during requests
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for range 10 {
+			api.SetCredentials(StaticCredentials(_DEFAULT_USER, _DEFAULT_PASS))
+		}
+	}()
+
+	for range 10 {
+		_, err = api.getCredentials(&APICall{Endpoint: "/stats"})
+		c.Assert(err, IsNil)
+	}
+
+	wg.Wait()
+
+	api.credentials = nil
+	_, err = api.GetStats()
+	c.Assert(err, Equals, ErrNilCredentials)
+}
+
+func (s *IcecastSuite) TestMountCredentials(c *C) {
+	var auths []string
+	var mu sync.Mutex
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+
+		mu.Lock()
+		auths = append(auths, r.URL.Path+":"+r.URL.Query().Get("mount")+":"+user+":"+pass)
+		mu.Unlock()
+
+		data, _ := xml.Marshal(&iceResponse{Message: "OK", Return: 1})
+		w.Write(data)
+	}))
+
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, "admin", "hackme")
+
+	c.Assert(api.SetMountCredentials("", StaticCredentials("source", "pass")), Equals, ErrEmptyMount)
+	c.Assert(api.SetMountCredentials("/live.mp3", nil), Equals, ErrNilCredentials)
+	c.Assert(api.SetMountCredentials("live.mp3", StaticCredentials("source", "live-pass")), IsNil)
+	c.Assert(api.SetMountCredentials("/talk.mp3", StaticCredentials("source", "talk-pass"), MOUNT_PERM_FALLBACK), IsNil)
+
+	c.Assert(api.UpdateMeta("/live.mp3", TrackMeta{Song: "Test"}), IsNil)
+	c.Assert(api.UpdateFallback("/live.mp3", "/backup.mp3"), IsNil)
+	c.Assert(api.KillSource("/live.mp3"), IsNil)
+	c.Assert(api.UpdateMeta("/talk.mp3", TrackMeta{Song: "Test"}), IsNil)
+	c.Assert(api.UpdateFallback("/talk.mp3", "/backup.mp3"), IsNil)
+	c.Assert(api.MoveClients("/talk.mp3", "/backup.mp3"), IsNil)
+	c.Assert(api.UpdateMeta("/other.mp3", TrackMeta{Song: "Test"}), IsNil)
+
+	api.RemoveMountCredentials("live.mp3")
+
+	c.Assert(api.UpdateMeta("/live.mp3", TrackMeta{Song: "Test"}), IsNil)
+
+	c.Assert(auths, DeepEquals, []string{
+		"/admin/metadata:/live.mp3:source:live-pass",
+		"/admin/fallback:/live.mp3:admin:hackme",
+		"/admin/killsource:/live.mp3:admin:hackme",
+		"/admin/metadata:/talk.mp3:source:talk-pass",
+		"/admin/fallback:/talk.mp3:source:talk-pass",
+		"/admin/moveclients:/talk.mp3:admin:hackme",
+		"/admin/metadata:/other.mp3:admin:hackme",
+		"/admin/metadata:/live.mp3:admin:hackme",
+	})
+
+	api.SetMountCredentials("/live.mp3", StaticCredentials("source", ""))
+	c.Assert(api.UpdateMeta("/live.mp3", TrackMeta{Song: "Test"}), ErrorMatches, "Invalid credentials: Password is empty")
+}
+
+func (s *IcecastSuite) TestUpdateMetaCompat(c *C) {
+	var queries []url.Values
+	var mu sync.Mutex
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path != "/admin.cgi":
+			w.WriteHeader(404)
+		case query.Get("pass") == "forbidden":
+			w.WriteHeader(403)
+		case query.Get("pass") == "html-error":
+			w.Write([]byte("<html><head><title>SHOUTcast Administrator</title></head><body>Invalid Password</body></html>"))
+		case query.Get("mount") == "/icecast.mp3":
+			w.Write(getResponseData("metadata.xml"))
+		case query.Get("mount") == "/icecast-error.mp3":
+			w.Write(getResponseData("metadata_error.xml"))
+		case query.Get("mount") == "/broken.mp3":
+			w.Write([]byte("<iceresponse><message>"))
+		case query.Get("mount") == "/down.mp3":
+			w.WriteHeader(503)
+		default:
+			w.Write([]byte("<html><body>Update successful</body></html>"))
+		}
+	}))
+
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, "admin", "hackme")
+	api.SetMountCredentials("/icecast.mp3", StaticCredentials("source", "source-pass"))
+
+	c.Assert(api.UpdateMetaCompat("", TrackMeta{Artist: "Artist", Title: "Title", URL: "https://example.com"}), IsNil)
+	c.Assert(queries[0], DeepEquals, url.Values{
+		"mode": {"updinfo"}, "pass": {"hackme"}, "song": {"Artist - Title"}, "url": {"https://example.com"},
+	})
+
+	c.Assert(api.UpdateMetaCompat("icecast.mp3", TrackMeta{Song: "Song", Artist: "Artist", Artwork: "a.jpg", Charset: "UTF-8"}), IsNil)
+	c.Assert(queries[1], DeepEquals, url.Values{
+		"mode": {"updinfo"}, "pass": {"source-pass"}, "mount": {"/icecast.mp3"}, "song": {"Song"}, "charset": {"UTF-8"},
+	})
+
+	c.Assert(api.UpdateMetaCompat("/icecast.mp3", TrackMeta{}), Equals, ErrEmptyCompatMeta)
+	c.Assert(api.UpdateMetaCompat("", TrackMeta{URL: "https://example.com"}), Equals, ErrEmptyCompatMeta)
+	c.Assert(queries, HasLen, 2)
+
+	c.Assert(api.UpdateMetaCompat("/icecast-error.mp3", TrackMeta{Song: "Song"}), ErrorMatches, "Metadata update error")
+	c.Assert(api.UpdateMetaCompat("/broken.mp3", TrackMeta{Song: "Song"}), ErrorMatches, "Can't parse API response: .*")
+	c.Assert(api.UpdateMetaCompat("/down.mp3", TrackMeta{Song: "Song"}), ErrorMatches, "API returned non-ok status code 503")
+	c.Assert(api.UpdateMetaCompat("/live mp3", TrackMeta{Song: "Song"}), ErrorMatches, "Mount contains invalid characters: .*")
+
+	api.SetCredentials(StaticCredentials("admin", "forbidden"))
+	c.Assert(api.UpdateMetaCompat("", TrackMeta{Title: "Title"}), Equals, ErrInvalidPassword)
+	c.Assert(queries[len(queries)-1].Get("song"), Equals, "Title")
+
+	api.SetCredentials(StaticCredentials("admin", "html-error"))
+	err := api.UpdateMetaCompat("", TrackMeta{Artist: "Artist"})
+	c.Assert(errors.Is(err, ErrInvalidPassword), Equals, true)
+	c.Assert(err, ErrorMatches, `Invalid password \(server response: "SHOUTcast Administrator Invalid Password"\)`)
+	c.Assert(queries[len(queries)-1].Get("song"), Equals, "Artist")
+
+	api.SetCredentials(StaticCredentials("admin", ""))
+	c.Assert(api.UpdateMetaCompat("", TrackMeta{Song: "Song"}), ErrorMatches, "Invalid credentials: Password is empty")
+
+	var calls []APICall
+
+	api.SetCredentials(StaticCredentials("admin", "hackme"))
+	api.Use(func(call *APICall, next APIHandler) error {
+		err := next(call)
+		calls = append(calls, *call)
+		return err
+	})
+
+	c.Assert(api.UpdateMetaCompat("", TrackMeta{Song: "Song"}), IsNil)
+	c.Assert(calls[0].Endpoint, Equals, "/admin.cgi")
+	c.Assert(calls[0].StatusCode, Equals, 200)
+	c.Assert(calls[0].Query["pass"], IsNil)
+
+	api, _ = NewAPI("http://127.0.0.1:40000", "admin", "hackme")
+	c.Assert(api.UpdateMetaCompat("", TrackMeta{Song: "Song"}), ErrorMatches, "Can't send request to server: .*")
+}
+
+func (s *IcecastSuite) TestSHOUTcastAPI(c *C) {
+	var queries []url.Values
+	var mu sync.Mutex
+
+	statsData := `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<SHOUTCASTSERVER>
+<TOTALSTREAMS>3</TOTALSTREAMS><ACTIVESTREAMS>2</ACTIVESTREAMS>
+<CURRENTLISTENERS>5</CURRENTLISTENERS><VERSION>2.6.1.777 (posix(linux x64))</VERSION>
+<STREAMSTATS>
+<STREAM id="1">
+<CURRENTLISTENERS>3</CURRENTLISTENERS><PEAKLISTENERS>10</PEAKLISTENERS>
+<MAXLISTENERS>100</MAXLISTENERS><SERVERGENRE>Rock</SERVERGENRE>
+<SERVERURL>https://example.com</SERVERURL><SERVERTITLE>Rock Radio</SERVERTITLE>
+<SONGTITLE>Artist - Title</SONGTITLE><STREAMHITS>42</STREAMHITS>
+<STREAMSTATUS>1</STREAMSTATUS><STREAMLISTED>1</STREAMLISTED>
+<STREAMPATH>/rock</STREAMPATH><STREAMUPTIME>3600</STREAMUPTIME>
+<BITRATE>128</BITRATE><SAMPLERATE>44100</SAMPLERATE><CONTENT>audio/mpeg</CONTENT>
+</STREAM>
+<STREAM id="2">
+<CURRENTLISTENERS>2</CURRENTLISTENERS><SONGTITLE>Jingle</SONGTITLE>
+<STREAMSTATUS>1</STREAMSTATUS><BITRATE>64</BITRATE><CONTENT>audio/aacp</CONTENT>
+</STREAM>
+<STREAM id="3"><STREAMSTATUS>0</STREAMSTATUS><STREAMPATH>/off</STREAMPATH></STREAM>
+</STREAMSTATS>
+</SHOUTCASTSERVER>`
+
+	listenersData := `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<SHOUTCASTSERVER><LISTENERS>
+<LISTENER><HOSTNAME>192.168.1.10</HOSTNAME><USERAGENT>VLC</USERAGENT><CONNECTTIME>120</CONNECTTIME><UID>7</UID><REFERER>https://example.com</REFERER></LISTENER>
+<LISTENER><HOSTNAME>192.168.1.11</HOSTNAME><USERAGENT>Winamp</USERAGENT><CONNECTTIME>30</CONNECTTIME><UID>8</UID></LISTENER>
+</LISTENERS></SHOUTCASTSERVER>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		switch {
+		case query.Get("pass") != "hackme":
+			w.WriteHeader(403)
+		case r.URL.Path == "/statistics":
+			w.Write([]byte(statsData))
+		case r.URL.Path != "/admin.cgi":
+			w.WriteHeader(404)
+		case query.Get("mode") == "viewxml" && query.Get("sid") == "9":
+			w.Write([]byte("<SHOUTCASTSERVER><LISTENERS>"))
+		case query.Get("mode") == "viewxml":
+			w.Write([]byte(listenersData))
+		default:
+			w.Write([]byte("<html><body>OK</body></html>"))
+		}
+	}))
+
+	defer srv.Close()
+
+	_, err := NewSHOUTcastAPI("", "hackme")
+	c.Assert(err, Equals, ErrEmptyURL)
+	_, err = NewSHOUTcastAPI(srv.URL, "")
+	c.Assert(err, Equals, ErrEmptyPassword)
+
+	api, err := NewSHOUTcastAPI(srv.URL+"/", "hackme")
+	c.Assert(err, IsNil)
+
+	api.SetUserAgent("Test", "1.0.0")
+
+	var server Server = api
+
+	stats, err := server.GetStats()
+	c.Assert(err, IsNil)
+	c.Assert(stats.Info.ID, Equals, "SHOUTcast Server 2.6.1.777 (posix(linux x64))")
+	c.Assert(stats.Stats.Listeners, Equals, 5)
+	c.Assert(stats.Stats.Sources, Equals, 2)
+	c.Assert(stats.Sources, HasLen, 2)
+	c.Assert(stats.GetSource("/off"), IsNil)
+
+	rock := stats.GetSource("/rock")
+	c.Assert(rock, NotNil)
+	c.Assert(rock.Mount, Equals, "/rock")
+	c.Assert(rock.AudioInfo.Codec, Equals, CODEC_MP3)
+	c.Assert(rock.AudioInfo.SampleRate, Equals, 44100)
+	c.Assert(rock.Bitrate.Kbps(), Equals, 128)
+	c.Assert(rock.Track.Artist, Equals, "Artist")
+	c.Assert(rock.Track.Title, Equals, "Title")
+	c.Assert(rock.Info.Name, Equals, "Rock Radio")
+	c.Assert(rock.Stats.Listeners, Equals, 3)
+	c.Assert(rock.Stats.ListenerPeak, Equals, 10)
+	c.Assert(rock.Stats.MaxListeners, Equals, 100)
+	c.Assert(rock.Stats.ListenerConnections, Equals, 42)
+	c.Assert(rock.Genre, Equals, "Rock")
+	c.Assert(rock.ListenURL, Equals, srv.URL+"/rock")
+	c.Assert(rock.Public, Equals, true)
+	c.Assert(time.Since(rock.StreamStarted) >= time.Hour, Equals, true)
+
+	jingle := stats.GetSource("/stream/2")
+	c.Assert(jingle, NotNil)
+	c.Assert(jingle.Track.Artist, Equals, "")
+	c.Assert(jingle.Track.Title, Equals, "Jingle")
+	c.Assert(jingle.Stats.MaxListeners, Equals, -1)
+	c.Assert(jingle.Public, Equals, false)
+
+	mounts, err := server.ListMounts()
+	c.Assert(err, IsNil)
+	c.Assert(mounts, HasLen, 2)
+
+	clients, err := server.ListClients("rock")
+	c.Assert(err, IsNil)
+	c.Assert(clients, HasLen, 2)
+	c.Assert(clients[0].ID, Equals, 7)
+	c.Assert(clients[0].IP, Equals, "192.168.1.10")
+	c.Assert(clients[0].UserAgent, Equals, "VLC")
+	c.Assert(clients[0].Referer, Equals, "https://example.com")
+	c.Assert(clients[0].Connected, Equals, 120)
+	c.Assert(queries[len(queries)-1].Get("sid"), Equals, "1")
+	c.Assert(queries[len(queries)-1].Get("page"), Equals, "3")
+
+	_, err = server.ListClients("/stream/9")
+	c.Assert(err, ErrorMatches, "Can't parse API response: .*")
+	_, err = server.ListClients("/unknown")
+	c.Assert(errors.Is(err, ErrSourceNotFound), Equals, true)
+	_, err = server.ListClients("")
+	c.Assert(err, Equals, ErrEmptyMount)
+
+	c.Assert(server.UpdateMeta("/rock", TrackMeta{Artist: "A", Title: "B"}), IsNil)
+	c.Assert(queries[len(queries)-1], DeepEquals, url.Values{
+		"mode": {"updinfo"}, "pass": {"hackme"}, "sid": {"1"}, "song": {"A - B"},
+	})
+
+	c.Assert(server.KillClient("/stream/2", 8), IsNil)
+	c.Assert(queries[len(queries)-1], DeepEquals, url.Values{
+		"mode": {"kickdst"}, "pass": {"hackme"}, "sid": {"2"}, "kickdst": {"8"},
+	})
+
+	c.Assert(server.KillSource("/rock"), IsNil)
+	c.Assert(queries[len(queries)-1], DeepEquals, url.Values{
+		"mode": {"kicksrc"}, "pass": {"hackme"}, "sid": {"1"},
+	})
+
+	api.password = "wrong"
+	_, err = api.GetStats()
+	c.Assert(err, Equals, ErrInvalidPassword)
+	c.Assert(api.KillSource("/rock"), Equals, ErrInvalidPassword)
+
+	c.Assert(getStreamMount(&dnasStream{ID: 4, StreamPath: " /jazz/ "}), Equals, "/jazz")
+	c.Assert(parseStreamSID("/stream/5/"), Equals, 5)
+	c.Assert(parseStreamSID("/stream/abc"), Equals, 0)
+	c.Assert(parseStreamSID("/stream/0"), Equals, 0)
+	c.Assert(parseStreamSID("/rock"), Equals, 0)
+
+	api, _ = NewSHOUTcastAPI("http://127.0.0.1:40000", "hackme")
+	_, err = api.GetStats()
+	c.Assert(err, ErrorMatches, "Can't send request to SHOUTcast API: .*")
+}
+
+func (s *IcecastSuite) TestServerFlavour(c *C) {
+	v, err := ParseServerID("Icecast 2.4.4")
+	c.Assert(err, IsNil)
+	c.Assert(v.Flavour, Equals, FLAVOUR_VANILLA)
+	c.Assert(v.Version.String(), Equals, "2.4.4")
+	c.Assert(v.String(), Equals, "Icecast 2.4.4")
+
+	v, err = ParseServerID("Icecast 2.4.0-kh12")
+	c.Assert(err, IsNil)
+	c.Assert(v.Flavour, Equals, FLAVOUR_KH)
+	c.Assert(v.Version.Simple(), Equals, "2.4.0")
+	c.Assert(v.Version.Build(), Equals, "kh12")
+	c.Assert(v.Version.PreRelease(), Equals, "")
+
+	v, err = ParseServerID("Icecast 2.5-beta3")
+	c.Assert(err, IsNil)
+	c.Assert(v.Flavour, Equals, FLAVOUR_BETA)
+	c.Assert(v.Version.String(), Equals, "2.5.0-beta3")
+
+	v, err = ParseServerID("Icecast 2.4.99.2")
+	c.Assert(err, IsNil)
+	c.Assert(v.Flavour, Equals, FLAVOUR_BETA)
+	c.Assert(v.Version.String(), Equals, "2.5.0-beta2")
+
+	v, err = ParseServerID("SHOUTcast Server 2.6.1.777 (posix(linux x64))")
+	c.Assert(err, IsNil)
+	c.Assert(v.Flavour, Equals, FLAVOUR_SHOUTCAST)
+	c.Assert(v.Version.String(), Equals, "2.6.1+777")
+
+	_, err = ParseServerID("")
+	c.Assert(errors.Is(err, ErrUnknownServer), Equals, true)
+	_, err = ParseServerID("nginx 1.25.0")
+	c.Assert(errors.Is(err, ErrUnknownServer), Equals, true)
+	_, err = ParseServerID("Icecast trunk")
+	c.Assert(err, ErrorMatches, `Can't find version in server ID "Icecast trunk"`)
+	_, err = ParseServerID("Icecast 2.x.1")
+	c.Assert(err, ErrorMatches, `Can't parse version in server ID "Icecast 2.x.1"`)
+	_, err = ParseServerID("Icecast 2.4.0.1.2")
+	c.Assert(err, ErrorMatches, `Can't parse version in server ID "Icecast 2.4.0.1.2"`)
+
+	vanilla := &ServerInfo{ID: "Icecast 2.4.4"}
+	legacy := &ServerInfo{ID: "Icecast 2.3.3"}
+	kh := &ServerInfo{ID: "Icecast 2.4.0-kh12"}
+	beta := &ServerInfo{ID: "Icecast 2.5-beta3"}
+	release := &ServerInfo{ID: "Icecast 2.5.0"}
+	dnas := &ServerInfo{ID: "SHOUTcast Server 2.6.1.777"}
+
+	c.Assert(vanilla.Supports(CAP_MANAGEAUTH), Equals, true)
+	c.Assert(vanilla.Supports(CAP_JSON_STATUS), Equals, true)
+	c.Assert(vanilla.Supports(CAP_CODEC_ID), Equals, false)
+	c.Assert(vanilla.Supports(CAP_ISO8601_DATES), Equals, false)
+	c.Assert(legacy.Supports(CAP_JSON_STATUS), Equals, false)
+	c.Assert(kh.Supports(CAP_JSON_STATUS), Equals, true)
+	c.Assert(kh.Supports(CAP_CODEC_ID), Equals, true)
+	c.Assert(kh.Supports(CAP_ISO8601_DATES), Equals, false)
+	c.Assert(beta.Supports(CAP_ISO8601_DATES), Equals, true)
+	c.Assert(release.Supports(CAP_ISO8601_DATES), Equals, true)
+	c.Assert(dnas.Supports(CAP_MOVE_CLIENTS), Equals, false)
+	c.Assert(dnas.Supports(CAP_UPDATE_FALLBACK), Equals, false)
+	c.Assert(vanilla.Supports(Capability(100)), Equals, false)
+	c.Assert((&ServerInfo{ID: "Unknown"}).Supports(CAP_MOVE_CLIENTS), Equals, true)
+	c.Assert((&ServerInfo{ID: "Unknown"}).Supports(CAP_MANAGEAUTH), Equals, false)
+	c.Assert((&ServerInfo{}).Supports(CAP_UPDATE_FALLBACK), Equals, true)
+	c.Assert((&ServerInfo{}).Supports(CAP_JSON_STATUS), Equals, false)
+
+	c.Assert(kh.Require(CAP_MANAGEAUTH), IsNil)
+	c.Assert(errors.Is(legacy.Require(CAP_JSON_STATUS), ErrUnsupported), Equals, true)
+	c.Assert(legacy.Require(CAP_JSON_STATUS), ErrorMatches, `Feature is not supported by server: json-status \(Icecast 2.3.3\)`)
+
+	var nilInfo *ServerInfo
+
+	c.Assert(nilInfo.Supports(CAP_MANAGEAUTH), Equals, false)
+	c.Assert(nilInfo.Supports(CAP_MOVE_CLIENTS), Equals, true)
+	c.Assert(nilInfo.Require(CAP_MANAGEAUTH), ErrorMatches, "Feature is not supported by server: manageauth")
+	_, err = nilInfo.Version()
+	c.Assert(errors.Is(err, ErrUnknownServer), Equals, true)
+
+	var nilVersion *ServerVersion
+
+	c.Assert(nilVersion.Supports(CAP_MANAGEAUTH), Equals, false)
+	c.Assert(nilVersion.String(), Equals, "")
+
+	c.Assert(FLAVOUR_UNKNOWN.String(), Equals, "Unknown")
+	c.Assert(FLAVOUR_VANILLA.String(), Equals, "Icecast")
+	c.Assert(FLAVOUR_KH.String(), Equals, "Icecast-KH")
+	c.Assert(FLAVOUR_BETA.String(), Equals, "Icecast Beta")
+	c.Assert(FLAVOUR_SHOUTCAST.String(), Equals, "SHOUTcast")
+
+	for _, cp := range []Capability{
+		CAP_MANAGEAUTH, CAP_JSON_STATUS, CAP_MOVE_CLIENTS,
+		CAP_UPDATE_FALLBACK, CAP_CODEC_ID, CAP_ISO8601_DATES,
+	} {
+		c.Assert(cp.String(), Not(Equals), "unknown")
+	}
+
+	c.Assert(Capability(0).String(), Equals, "unknown")
+
+	var requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(getResponseData("stats.xml"))
+	}))
+
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, "admin", "hackme")
+
+	ok, err := api.Supports(CAP_CODEC_ID)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(api.Require(CAP_ISO8601_DATES), ErrorMatches, `Feature is not supported by server: iso8601-dates \(Icecast 2.4.0-kh12\)`)
+	c.Assert(api.Require(CAP_MANAGEAUTH), IsNil)
+	c.Assert(requests, Equals, 1)
+
+	api, _ = NewAPI("http://127.0.0.1:40000", "admin", "hackme")
+	_, err = api.Supports(CAP_CODEC_ID)
+	c.Assert(err, NotNil)
+	c.Assert(api.Require(CAP_CODEC_ID), NotNil)
+	c.Assert(api.require(CAP_MOVE_CLIENTS), IsNil)
+
+	// Gated operations
+	var calls []string
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Write([]byte(`<icestats><server_id>SHOUTcast Server 2.6.1.777</server_id></icestats>`))
+	}))
+
+	defer srv.Close()
+
+	api, _ = NewAPI(srv.URL, "admin", "hackme")
+
+	// Operations aren't checked until server info is fetched
+	c.Assert(api.require(CAP_MOVE_CLIENTS), IsNil)
+	c.Assert(calls, HasLen, 0)
+
+	_, err = api.GetStats()
+	c.Assert(err, IsNil)
+
+	err = api.MoveClients("/live.mp3", "/backup.mp3")
+	c.Assert(errors.Is(err, ErrUnsupported), Equals, true)
+	err = api.UpdateFallback("/live.mp3", "/backup.mp3")
+	c.Assert(errors.Is(err, ErrUnsupported), Equals, true)
+	c.Assert(calls, DeepEquals, []string{"/admin/stats"})
+}
+
+func (s *IcecastSuite) TestDateParsing(c *C) {
+	expected := time.Date(2020, 4, 18, 11, 50, 3, 0, time.UTC)
+
+	for _, date := range []string{
+		"18/Apr/2020:11:50:03 +0000",
+		"18/Apr/2020:14:50:03 +0300",
+		"Sat, 18 Apr 2020 11:50:03 +0000",
+		"Sat, 18 Apr 2020 11:50:03 GMT",
+		"2020-04-18T11:50:03Z",
+		"2020-04-18T14:50:03+03:00",
+		"2020-04-18T14:50:03+0300",
+		" 2020-04-18T11:50:03.000+00:00 ",
+		"Sat Apr 18 11:50:03 2020",
+	} {
+		d, err := parseDate(date)
+		c.Assert(err, IsNil, Commentf("Date: %q", date))
+		c.Assert(d.Equal(expected), Equals, true, Commentf("Date: %q → %v", date, d))
+	}
+
+	d, err := parseDate("18 Apr 20 11:50 +0000")
+	c.Assert(err, IsNil)
+	c.Assert(d.Equal(expected.Truncate(time.Minute)), Equals, true)
+
+	d, err = parseDate("")
+	c.Assert(err, IsNil)
+	c.Assert(d.IsZero(), Equals, true)
+
+	_, err = parseDate("yesterday")
+	c.Assert(err, ErrorMatches, `Unsupported date format "yesterday"`)
+
+	d, warning := parseDateField("stream_start", "2020-04-18T11:50:03+0000", "broken")
+	c.Assert(warning, Equals, "")
+	c.Assert(d.Equal(expected), Equals, true)
+
+	d, warning = parseDateField("stream_start", "broken", "18/Apr/2020:11:50:03 +0000")
+	c.Assert(warning, Equals, `Can't parse stream_start_iso8601: Unsupported date format "broken"`)
+	c.Assert(d.Equal(expected), Equals, true)
+
+	d, warning = parseDateField("stream_start", "", "")
+	c.Assert(warning, Equals, "")
+	c.Assert(d.IsZero(), Equals, true)
+
+	stats := convertStats(&iceStats{
+		ServerStart:        "17/Apr/2020:09:48:18 +0000",
+		ServerStartISO8601: "2020-04-17T12:48:18+0300",
+		SourcesData: []*iceSource{
+			{
+				Mount:              "/iso.mp3",
+				StreamStart:        "18/Apr/2020:11:50:03 +0000",
+				StreamStartISO8601: "2020-04-18T11:50:03+0000",
+				MetadataUpdated:    "Sat, 18 Apr 2020 11:50:03 +0000",
+			},
+			{
+				Mount:           "/broken.mp3",
+				StreamStart:     "someday",
+				MetadataUpdated: "18.04.2020",
+			},
+		},
+	})
+
+	c.Assert(stats.Started.Equal(time.Date(2020, 4, 17, 9, 48, 18, 0, time.UTC)), Equals, true)
+	c.Assert(stats.GetSource("/iso.mp3").StreamStarted.Equal(expected), Equals, true)
+	c.Assert(stats.GetSource("/iso.mp3").MetadataUpdated.Equal(expected), Equals, true)
+	c.Assert(stats.GetSource("/broken.mp3").StreamStarted.IsZero(), Equals, true)
+	c.Assert(stats.GetSource("/broken.mp3").MetadataUpdated.IsZero(), Equals, true)
+	c.Assert(stats.Warnings, DeepEquals, []string{
+		`/broken.mp3: Can't parse metadata_updated: Unsupported date format "18.04.2020"`,
+		`/broken.mp3: Can't parse stream_start: Unsupported date format "someday"`,
+	})
+
+	stats = convertStats(&iceStats{ServerStart: "never"})
+	c.Assert(stats.Started.IsZero(), Equals, true)
+	c.Assert(stats.Warnings, DeepEquals, []string{`Can't parse server_start: Unsupported date format "never"`})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(getResponseData("stats.xml"))
+	}))
+
+	defer srv.Close()
+
+	api, _ := NewAPI(srv.URL, "admin", "hackme")
+	stats, err = api.GetStats()
+	c.Assert(err, IsNil)
+	c.Assert(stats.Warnings, IsNil)
+	c.Assert(stats.GetSource("/source1.ogg").StreamStarted.IsZero(), Equals, false)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func runHTTPServer(c *C, port string) {
+	server := &http.Server{
+		Handler:        http.NewServeMux(),
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/metadata", handlerMetadata)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/fallback", handlerFallback)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/listclients", handlerListClients)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/moveclients", handlerMoveClients)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/killclient", handlerKillClient)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/killsource", handlerKillSource)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/stats", handlerStats)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/listmounts", handlerListMounts)
+	server.Handler.(*http.ServeMux).HandleFunc("/admin/_garbage", handlerGarbageResponse)
+
+	err = server.Serve(listener)
+
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+}
+
+func handlerMetadata(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	mount := r.URL.Query().Get("mount")
+	artist := r.URL.Query().Get("artist")
+	title := r.URL.Query().Get("title")
+
+	if mount == "/source99.ogg" {
+		w.WriteHeader(200)
+		w.Write(getResponseData("metadata_error.xml"))
+		return
+	}
+
+	switch {
+	case mode != "updinfo",
+		mount != "/source1.ogg",
+		artist == "",
+		title == "":
+		w.WriteHeader(400)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("metadata.xml"))
+}
+
+func handlerFallback(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	mount := r.URL.Query().Get("mount")
+	fallback := r.URL.Query().Get("fallback")
+
+	if mount != "/source1.ogg" || fallback == "" {
+		w.WriteHeader(400)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("fallback.xml"))
+}
+
+func handlerListClients(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	mount := r.URL.Query().Get("mount")
+
+	if mount != "/source1.ogg" {
+		w.WriteHeader(400)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("listclients.xml"))
+}
+
+func handlerMoveClients(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	mount := r.URL.Query().Get("mount")
+	destination := r.URL.Query().Get("destination")
+
+	switch {
+	case mount != "/source1.ogg",
+		destination != "/source2.ogg":
+		w.WriteHeader(400)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("moveclients.xml"))
+}
+
+func handlerKillClient(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	mount := r.URL.Query().Get("mount")
+	id := r.URL.Query().Get("id")
+
+	switch {
+	case mount != "/source1.ogg",
+		id != "100":
+		w.WriteHeader(400)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("killclient.xml"))
+}
+
+func handlerKillSource(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	mount := r.URL.Query().Get("mount")
+
+	if mount != "/source1.ogg" {
+		w.WriteHeader(400)
+		return
+	}
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("killsource.xml"))
+}
+
+func handlerStats(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	if statsError {
+		w.WriteHeader(400)
+		return
+	}
+
+	statsError = true
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("stats.xml"))
+}
+
+func handlerListMounts(w http.ResponseWriter, r *http.Request) {
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	if mountError {
+		w.WriteHeader(400)
+		return
+	}
+
+	mountError = true
+
+	w.WriteHeader(200)
+	w.Write(getResponseData("listmounts.xml"))
+}
+
+func handlerGarbageResponse(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(200)
+	w.Write([]byte("@@@@"))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// fakeIcecast is fake Icecast server with mutable state
+type fakeIcecast struct {
+	*httptest.Server
+
+	sources   map[string]*iceSource
+	listeners map[string][]*Listener
+	stalled   map[string]bool
+	failing   map[string]bool
+	calls     []string
+	mu        sync.Mutex
+}
+
+func newFakeIcecast() *fakeIcecast {
+	f := &fakeIcecast{
+		sources:   make(map[string]*iceSource),
+		listeners: make(map[string][]*Listener),
+		stalled:   make(map[string]bool),
+		failing:   make(map[string]bool),
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+
+	return f
+}
+
+func (f *fakeIcecast) AddSource(mount string, listeners int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sources[mount] = &iceSource{
+		Mount:           mount,
+		ServerType:      "audio/mpeg",
+		Bitrate:         "128",
+		IncomingBitrate: 128000,
+		Listeners:       listeners,
+		MaxListeners:    "100",
+		ListenURL:       "http://127.0.0.1:8000" + mount,
+		Public:          1,
+	}
+
+	f.listeners[mount] = nil
+
+	for i := 0; i < listeners; i++ {
+		f.listeners[mount] = append(f.listeners[mount], &Listener{
+			ID: len(f.calls)*1000 + i + 1, IP: fmt.Sprintf("10.0.0.%d", i+1),
+		})
+	}
+}
+
+func (f *fakeIcecast) RemoveSource(mount string) {
+	f.mu.Lock()
+	delete(f.sources, mount)
+	delete(f.listeners, mount)
+	f.mu.Unlock()
+}
+
+func (f *fakeIcecast) UpdateSource(mount string, fn func(s *iceSource)) {
+	f.mu.Lock()
+	fn(f.sources[mount])
+	f.mu.Unlock()
+}
+
+func (f *fakeIcecast) SetStalled(mount string, stalled bool) {
+	f.mu.Lock()
+	f.stalled[mount] = stalled
+	f.mu.Unlock()
+}
+
+func (f *fakeIcecast) SetFailing(endpoint string, failing bool) {
+	f.mu.Lock()
+	f.failing[endpoint] = failing
+	f.mu.Unlock()
+}
+
+func (f *fakeIcecast) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeIcecast) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !isBasicAuthSet(r) {
+		w.WriteHeader(403)
+		return
+	}
+
+	endpoint := strings.TrimPrefix(r.URL.Path, "/admin/")
+	query := r.URL.Query()
+	mount := query.Get("mount")
+
+	if f.failing[endpoint] {
+		w.WriteHeader(500)
+		return
+	}
+
+	var resp any
+
+	switch endpoint {
+	case "stats":
+		stats := &iceStats{ServerID: "Icecast 2.4.4"}
+
+		for _, s := range f.sources {
+			if !f.stalled[s.Mount] {
+				s.TotalBytesRead += 16000
+			}
+
+			s.Listeners = len(f.listeners[s.Mount])
+			stats.SourcesData = append(stats.SourcesData, s)
+		}
+
+		resp = stats
+
+	case "listmounts":
+		mounts := &iceMounts{}
+
+		for _, s := range f.sources {
+			mounts.Mounts = append(mounts.Mounts, &Mount{
+				Path: s.Mount, Listeners: len(f.listeners[s.Mount]), ContentType: s.ServerType,
+			})
+		}
+
+		resp = mounts
+
+	case "listclients":
+		if f.sources[mount] == nil {
+			resp = &iceResponse{Message: "Source does not exist"}
+			break
+		}
+
+		resp = &iceListeners{Listeners: f.listeners[mount]}
+
+	case "moveclients":
+		dest := query.Get("destination")
+
+		if f.sources[mount] == nil || f.sources[dest] == nil {
+			resp = &iceResponse{Message: "Source does not exist"}
+			break
+		}
+
+		f.calls = append(f.calls, "moveclients:"+mount+">"+dest)
+		f.listeners[dest] = append(f.listeners[dest], f.listeners[mount]...)
+		f.listeners[mount] = nil
+		resp = &iceResponse{Message: "Clients moved", Return: 1}
+
+	case "metadata":
+		if f.sources[mount] == nil {
+			resp = &iceResponse{Message: "Source does not exist"}
+			break
+		}
+
+		f.calls = append(f.calls, "metadata:"+mount+":"+query.Get("song"))
+		f.sources[mount].YpCurrentlyPlaying = query.Get("song")
+		resp = &iceResponse{Message: "Metadata update successful", Return: 1}
+
+	case "fallback", "killsource", "killclient":
+		if f.sources[mount] == nil {
+			resp = &iceResponse{Message: "Source does not exist"}
+			break
+		}
+
+		f.calls = append(f.calls, endpoint+":"+mount)
+		resp = &iceResponse{Message: "Done", Return: 1}
+
+	default:
+		w.WriteHeader(404)
+		return
+	}
+
+	data, _ := xml.Marshal(resp)
+
+	w.WriteHeader(200)
+	w.Write(data)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func sendAuthRequest(handler http.Handler, data url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/auth", strings.NewReader(data.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func isBasicAuthSet(r *http.Request) bool {
 	user, pass, _ := r.BasicAuth()
 	return user == _DEFAULT_USER && pass == _DEFAULT_PASS
 }
@@ -520,3 +3651,15 @@ func getResponseData(filename string) []byte {
 	data, _ := os.ReadFile("testdata/" + filename)
 	return data
 }
+
+func countItems(items []string, item string) int {
+	var count int
+
+	for _, i := range items {
+		if i == item {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,275 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/req"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// REDACTED_VALUE is value used instead of sensitive query parameters
+const REDACTED_VALUE = "[REDACTED]"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Interceptor is function which wraps API request. Interceptor can modify call
+// before passing it to next handler, or short-circuit request by returning
+// without calling next. If next is not called and returned error is nil, request
+// is considered successful.
+type Interceptor func(call *APICall, next APIHandler) error
+
+// APIHandler is handler of API call
+type APIHandler func(call *APICall) error
+
+// APICall contains info about API request
+type APICall struct {
+	// Endpoint is API endpoint (e.g. /stats)
+	Endpoint string
+
+	// Query is request query
+	Query req.Query
+
+	// StatusCode is HTTP status code of response (0 if request wasn't sent)
+	StatusCode int
+
+	// Duration is duration of request including response decoding
+	Duration time.Duration
+}
+
+// LatencyHistogram collects per-endpoint latency histograms
+type LatencyHistogram struct {
+	buckets []time.Duration
+	data    map[string]*LatencyStats
+	mu      sync.Mutex
+}
+
+// LatencyStats contains latency histogram for endpoint
+type LatencyStats struct {
+	// Buckets contains upper bounds of histogram buckets
+	Buckets []time.Duration
+
+	// Counts contains number of requests in every bucket. Last element is
+	// number of requests slower than the last bucket.
+	Counts []uint64
+
+	// Count is total number of requests
+	Count uint64
+
+	// Errors is number of failed requests
+	Errors uint64
+
+	// Sum is total duration of all requests
+	Sum time.Duration
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DefaultLatencyBuckets is default set of latency histogram buckets
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// ErrRequestSkipped is returned by streaming methods if request was
+// short-circuited by interceptor
+var ErrRequestSkipped = errors.New("Request was skipped by interceptor")
+
+// redactedParams is list of query parameters with sensitive data
+var redactedParams = []string{"pass", "password", "passwd", "sig", "token"}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Use adds interceptors to API client. Interceptors are executed in the order
+// they were added.
+func (api *API) Use(interceptors ...Interceptor) {
+	for _, i := range interceptors {
+		if i != nil {
+			api.interceptors = append(api.interceptors, i)
+		}
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// RedactedQuery returns copy of query with sensitive values redacted
+func (c *APICall) RedactedQuery() req.Query {
+	result := maps.Clone(c.Query)
+
+	for name := range result {
+		for _, p := range redactedParams {
+			if strings.EqualFold(name, p) {
+				result[name] = REDACTED_VALUE
+			}
+		}
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// LogInterceptor creates interceptor which logs all requests using given logger.
+// Successful requests are logged with debug level, failed requests with error
+// level.
+func LogInterceptor(logger *slog.Logger) Interceptor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(call *APICall, next APIHandler) error {
+		err := next(call)
+
+		attrs := []slog.Attr{
+			slog.String("endpoint", call.Endpoint),
+			slog.String("query", call.RedactedQuery().Encode()),
+			slog.Int("status", call.StatusCode),
+			slog.Duration("duration", call.Duration),
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			logger.LogAttrs(context.Background(), slog.LevelError, "Icecast API request failed", attrs...)
+		} else {
+			logger.LogAttrs(context.Background(), slog.LevelDebug, "Icecast API request", attrs...)
+		}
+
+		return err
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewLatencyHistogram creates new latency histogram with given buckets
+// (DefaultLatencyBuckets are used if no buckets are given)
+func NewLatencyHistogram(buckets ...time.Duration) *LatencyHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	return &LatencyHistogram{
+		buckets: append([]time.Duration(nil), buckets...),
+		data:    make(map[string]*LatencyStats),
+	}
+}
+
+// Interceptor returns interceptor which collects request latency
+func (h *LatencyHistogram) Interceptor() Interceptor {
+	return func(call *APICall, next APIHandler) error {
+		err := next(call)
+		h.Add(call.Endpoint, call.Duration, err != nil)
+		return err
+	}
+}
+
+// Add adds request with given duration to histogram
+func (h *LatencyHistogram) Add(endpoint string, dur time.Duration, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.data[endpoint]
+
+	if stats == nil {
+		stats = &LatencyStats{
+			Buckets: h.buckets,
+			Counts:  make([]uint64, len(h.buckets)+1),
+		}
+
+		h.data[endpoint] = stats
+	}
+
+	index := len(h.buckets)
+
+	for i, b := range h.buckets {
+		if dur <= b {
+			index = i
+			break
+		}
+	}
+
+	stats.Counts[index]++
+	stats.Count++
+	stats.Sum += dur
+
+	if failed {
+		stats.Errors++
+	}
+}
+
+// Stats returns copy of collected histograms grouped by endpoint
+func (h *LatencyHistogram) Stats() map[string]LatencyStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make(map[string]LatencyStats, len(h.data))
+
+	for endpoint, stats := range h.data {
+		s := *stats
+		s.Counts = append([]uint64(nil), stats.Counts...)
+		result[endpoint] = s
+	}
+
+	return result
+}
+
+// Reset removes all collected data
+func (h *LatencyHistogram) Reset() {
+	h.mu.Lock()
+	h.data = make(map[string]*LatencyStats)
+	h.mu.Unlock()
+}
+
+// Mean returns mean request duration
+func (s LatencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return s.Sum / time.Duration(s.Count)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// intercept executes handler through interceptor chain
+func (api *API) intercept(endpoint string, query req.Query, handler APIHandler) error {
+	call := &APICall{Endpoint: endpoint, Query: maps.Clone(query)}
+
+	if call.Query == nil {
+		call.Query = req.Query{}
+	}
+
+	next := func(c *APICall) error {
+		start := time.Now()
+		err := handler(c)
+		c.Duration = time.Since(start)
+		return err
+	}
+
+	for i := len(api.interceptors) - 1; i >= 0; i-- {
+		interceptor, n := api.interceptors[i], next
+		next = func(c *APICall) error { return interceptor(c, n) }
+	}
+
+	return next(call)
+}
@@ -0,0 +1,160 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MountHandle is handle for operations with single mount point
+type MountHandle struct {
+	api  *API
+	path string
+	err  error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrEmptyMount   = errors.New("Mount is empty")
+	ErrInvalidMount = errors.New("Mount contains invalid characters")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Mount returns handle for given mount point. Mount is normalized and validated,
+// validation error is returned by every handle method.
+func (api *API) Mount(mount string) *MountHandle {
+	path, err := validateMount(mount)
+	return &MountHandle{api: api, path: path, err: err}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Path returns normalized mount path
+func (m *MountHandle) Path() string {
+	return m.path
+}
+
+// Err returns mount validation error
+func (m *MountHandle) Err() error {
+	return m.err
+}
+
+// Stats fetches info about mount source
+func (m *MountHandle) Stats() (*Source, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	stats, err := m.api.GetStats()
+
+	if err != nil {
+		return nil, err
+	}
+
+	source := stats.GetSource(m.path)
+
+	if source == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, m.path)
+	}
+
+	return source, nil
+}
+
+// Clients fetches list of listeners connected to mount
+func (m *MountHandle) Clients() ([]*Listener, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.api.ListClients(m.path)
+}
+
+// UpdateMeta updates meta of mount source
+func (m *MountHandle) UpdateMeta(meta TrackMeta) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	return m.api.UpdateMeta(m.path, meta)
+}
+
+// SetFallback updates fallback of mount source
+func (m *MountHandle) SetFallback(fallback string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	return m.api.UpdateFallback(m.path, fallback)
+}
+
+// MoveClientsTo moves clients from mount to given destination mount
+func (m *MountHandle) MoveClientsTo(dest string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	return m.api.MoveClients(m.path, dest)
+}
+
+// KillClient kills client with given ID connected to mount
+func (m *MountHandle) KillClient(id int) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	return m.api.KillClient(m.path, id)
+}
+
+// Kill kills mount source
+func (m *MountHandle) Kill() error {
+	if m.err != nil {
+		return m.err
+	}
+
+	return m.api.KillSource(m.path)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// normalizeMount removes surrounding whitespaces and adds leading slash to mount
+// point name
+func normalizeMount(mount string) string {
+	mount = strings.TrimSpace(mount)
+
+	if mount == "" || strings.HasPrefix(mount, "/") {
+		return mount
+	}
+
+	return "/" + mount
+}
+
+// validateMount normalizes and validates mount point name
+func validateMount(mount string) (string, error) {
+	mount = normalizeMount(mount)
+
+	if mount == "" || mount == "/" {
+		return "", ErrEmptyMount
+	}
+
+	if strings.ContainsAny(mount, "?#\\") || strings.ContainsFunc(mount, isInvalidMountRune) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidMount, mount)
+	}
+
+	return mount, nil
+}
+
+// isInvalidMountRune returns true if given rune can't be used in mount name
+func isInvalidMountRune(r rune) bool {
+	return r <= ' ' || r == 0x7F
+}
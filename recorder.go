@@ -0,0 +1,597 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// RECORDING_TIME_LAYOUT is layout of timestamp used in recording file names
+const RECORDING_TIME_LAYOUT = "20060102-150405"
+
+// RECORDING_TRACKS_EXT is extension of recording sidecar file with track changes
+const RECORDING_TRACKS_EXT = ".tracks"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Recorder records all mounts to disk
+type Recorder struct {
+	// Mounts is optional list of recorded mounts (if empty, all mounts are recorded)
+	Mounts []string
+
+	// RotateEvery is max duration of recording file
+	RotateEvery time.Duration
+
+	// MaxSize is max size of recording file in bytes (0 - unlimited)
+	MaxSize int64
+
+	// Retention is period of time recordings are kept (0 - keep forever)
+	Retention time.Duration
+
+	// ReconnectDelay is delay between reconnection attempts
+	ReconnectDelay time.Duration
+
+	// ReadTimeout is max period of time without data from server after which
+	// connection is re-established (0 - no timeout)
+	ReadTimeout time.Duration
+
+	// DiscoverInterval is interval of discovering new mounts
+	DiscoverInterval time.Duration
+
+	// MetaInterval is interval of checking track changes
+	MetaInterval time.Duration
+
+	// OnError is optional callback for recording errors
+	OnError func(mount string, err error)
+
+	api    *API
+	dir    string
+	client *http.Client
+	active map[string]*recorderMount
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// RecordingTrack is track change record from recording sidecar file
+type RecordingTrack struct {
+	Time   time.Time  `json:"time"`
+	Offset int64      `json:"offset"`
+	Track  *TrackInfo `json:"track"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// recorderMount contains state of recorded mount
+type recorderMount struct {
+	mount   string
+	ext     string
+	file    *os.File
+	tracks  *os.File
+	size    int64
+	started time.Time
+	track   *TrackInfo
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var ErrEmptyRecordDir = errors.New("Recordings directory is empty")
+
+// recordingNameRegex is regexp for recording file names
+var recordingNameRegex = regexp.MustCompile(`_\d{8}-\d{6}(-\d+)?(\.[a-z0-9]+)?(\.tracks)?$`)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewRecorder creates new recorder which saves recordings to given directory
+func NewRecorder(api *API, dir string) (*Recorder, error) {
+	switch {
+	case api == nil:
+		return nil, ErrNilAPI
+	case dir == "":
+		return nil, ErrEmptyRecordDir
+	}
+
+	return &Recorder{
+		RotateEvery:      time.Hour,
+		ReconnectDelay:   5 * time.Second,
+		ReadTimeout:      30 * time.Second,
+		DiscoverInterval: 30 * time.Second,
+		MetaInterval:     10 * time.Second,
+		api:              api,
+		dir:              dir,
+		client:           &http.Client{},
+		active:           make(map[string]*recorderMount),
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Run records mounts until context is canceled
+func (r *Recorder) Run(ctx context.Context) error {
+	err := os.MkdirAll(r.dir, 0750)
+
+	if err != nil {
+		return fmt.Errorf("Can't create recordings directory: %w", err)
+	}
+
+	discoverTicker := time.NewTicker(r.DiscoverInterval)
+	metaTicker := time.NewTicker(r.MetaInterval)
+
+	defer discoverTicker.Stop()
+	defer metaTicker.Stop()
+
+	r.discover(ctx)
+	r.prune()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.stop()
+			return ctx.Err()
+
+		case <-discoverTicker.C:
+			r.discover(ctx)
+			r.prune()
+
+		case <-metaTicker.C:
+			r.updateTracks()
+		}
+	}
+}
+
+// Recording returns slice with mounts which are recorded at the moment
+func (r *Recorder) Recording() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []string
+
+	for mount := range r.active {
+		result = append(result, mount)
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// discover starts recording of new mounts and stops recording of removed mounts
+func (r *Recorder) discover(ctx context.Context) {
+	mounts, err := r.api.ListMounts()
+
+	if err != nil {
+		r.onError("", fmt.Errorf("Can't discover mounts: %w", err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := make(map[string]bool)
+
+	for _, m := range mounts {
+		mount := normalizeMount(m.Path)
+
+		if !r.isRecorded(mount) {
+			continue
+		}
+
+		found[mount] = true
+
+		if r.active[mount] != nil {
+			continue
+		}
+
+		mctx, cancel := context.WithCancel(ctx)
+		rm := &recorderMount{
+			mount:  mount,
+			ext:    getRecordingExt(mount, m.ContentType),
+			cancel: cancel,
+		}
+
+		r.active[mount] = rm
+		r.wg.Add(1)
+
+		go r.record(mctx, rm)
+	}
+
+	for mount, rm := range r.active {
+		if !found[mount] {
+			rm.cancel()
+			delete(r.active, mount)
+		}
+	}
+}
+
+// record records given mount until context is canceled
+func (r *Recorder) record(ctx context.Context, rm *recorderMount) {
+	defer r.wg.Done()
+	defer rm.close()
+
+	for {
+		err := r.stream(ctx, rm)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.onError(rm.mount, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.ReconnectDelay):
+		}
+	}
+}
+
+// stream connects to mount and writes received data to disk
+func (r *Recorder) stream(ctx context.Context, rm *recorderMount) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var idle *time.Timer
+	var stalled atomic.Bool
+
+	// Request is canceled if server doesn't send any data within read timeout
+	if r.ReadTimeout > 0 {
+		idle = time.AfterFunc(r.ReadTimeout, func() {
+			stalled.Store(true)
+			cancel()
+		})
+
+		defer idle.Stop()
+	}
+
+	streamURL := strings.TrimRight(r.api.url, "/") + rm.mount
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+
+	if err != nil {
+		return fmt.Errorf("Can't create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", USER_AGENT)
+
+	resp, err := r.client.Do(req)
+
+	if err != nil {
+		if stalled.Load() {
+			return fmt.Errorf("No response from server within %s", r.ReadTimeout)
+		}
+
+		return fmt.Errorf("Can't connect to mount: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Server returned non-ok status code %d", resp.StatusCode)
+	}
+
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := resp.Body.Read(buf)
+
+		if idle != nil {
+			idle.Reset(r.ReadTimeout)
+		}
+
+		if n > 0 {
+			werr := rm.write(buf[:n], r)
+
+			if werr != nil {
+				return werr
+			}
+		}
+
+		switch {
+		case err == io.EOF:
+			return fmt.Errorf("Stream closed by server")
+		case err != nil && stalled.Load():
+			return fmt.Errorf("No data received from server within %s", r.ReadTimeout)
+		case err != nil:
+			return fmt.Errorf("Can't read stream: %w", err)
+		}
+	}
+}
+
+// updateTracks fetches stats and writes track changes to sidecar files
+func (r *Recorder) updateTracks() {
+	stats, err := r.api.GetStats()
+
+	if err != nil {
+		r.onError("", fmt.Errorf("Can't fetch stats: %w", err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for mount, rm := range r.active {
+		source := stats.Sources[mount]
+
+		if source == nil || source.Track == nil {
+			continue
+		}
+
+		err = rm.setTrack(source.Track)
+
+		if err != nil {
+			r.onError(mount, err)
+		}
+	}
+}
+
+// prune removes recordings older than retention window
+func (r *Recorder) prune() {
+	if r.Retention <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(r.dir)
+
+	if err != nil {
+		r.onError("", fmt.Errorf("Can't read recordings directory: %w", err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inUse := make(map[string]bool)
+
+	for _, rm := range r.active {
+		rm.mu.Lock()
+		if rm.file != nil {
+			inUse[filepath.Base(rm.file.Name())] = true
+			inUse[filepath.Base(rm.tracks.Name())] = true
+		}
+		rm.mu.Unlock()
+	}
+
+	deadline := time.Now().Add(-r.Retention)
+
+	for _, e := range entries {
+		if !e.Type().IsRegular() || inUse[e.Name()] || !recordingNameRegex.MatchString(e.Name()) {
+			continue
+		}
+
+		info, err := e.Info()
+
+		if err != nil || info.ModTime().After(deadline) {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(r.dir, e.Name()))
+
+		if err != nil {
+			r.onError("", fmt.Errorf("Can't remove old recording: %w", err))
+		}
+	}
+}
+
+// stop stops recording of all mounts
+func (r *Recorder) stop() {
+	r.mu.Lock()
+
+	for mount, rm := range r.active {
+		rm.cancel()
+		delete(r.active, mount)
+	}
+
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// isRecorded returns true if given mount must be recorded
+func (r *Recorder) isRecorded(mount string) bool {
+	if len(r.Mounts) == 0 {
+		return true
+	}
+
+	for _, m := range r.Mounts {
+		if normalizeMount(m) == mount {
+			return true
+		}
+	}
+
+	return false
+}
+
+// onError executes error callback
+func (r *Recorder) onError(mount string, err error) {
+	if r.OnError != nil && err != nil {
+		r.OnError(mount, err)
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// write writes stream data to recording file
+func (rm *recorderMount) write(data []byte, r *Recorder) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.file == nil || rm.isRotationRequired(r, len(data)) {
+		err := rm.rotate(r.dir)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := rm.file.Write(data)
+	rm.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("Can't write recording: %w", err)
+	}
+
+	return nil
+}
+
+// setTrack writes track change to sidecar file
+func (rm *recorderMount) setTrack(track *TrackInfo) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.track != nil && *rm.track == *track {
+		return nil
+	}
+
+	rm.track = track
+
+	if rm.tracks == nil {
+		return nil
+	}
+
+	return rm.writeTrack()
+}
+
+// isRotationRequired returns true if recording file must be rotated
+func (rm *recorderMount) isRotationRequired(r *Recorder, size int) bool {
+	switch {
+	case r.RotateEvery > 0 && time.Since(rm.started) >= r.RotateEvery,
+		r.MaxSize > 0 && rm.size > 0 && rm.size+int64(size) > r.MaxSize:
+		return true
+	}
+
+	return false
+}
+
+// rotate closes current recording file and creates new one
+func (rm *recorderMount) rotate(dir string) error {
+	rm.closeFiles()
+
+	now := time.Now().UTC()
+	name := filepath.Join(dir, getRecordingName(rm.mount, now)+rm.ext)
+
+	// Add sequence number if file was rotated twice within the same second
+	for i := 2; ; i++ {
+		_, err := os.Stat(name)
+
+		if err != nil {
+			break
+		}
+
+		name = filepath.Join(dir, fmt.Sprintf("%s-%d%s", getRecordingName(rm.mount, now), i, rm.ext))
+	}
+
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0640)
+
+	if err != nil {
+		return fmt.Errorf("Can't create recording file: %w", err)
+	}
+
+	tracks, err := os.OpenFile(name+RECORDING_TRACKS_EXT, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Can't create recording tracks file: %w", err)
+	}
+
+	rm.file, rm.tracks, rm.size, rm.started = file, tracks, 0, now
+
+	if rm.track != nil {
+		return rm.writeTrack()
+	}
+
+	return nil
+}
+
+// writeTrack writes current track to sidecar file
+func (rm *recorderMount) writeTrack() error {
+	data, err := json.Marshal(&RecordingTrack{
+		Time:   time.Now().UTC(),
+		Offset: rm.size,
+		Track:  rm.track,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Can't encode track info: %w", err)
+	}
+
+	_, err = rm.tracks.Write(append(data, '\n'))
+
+	if err != nil {
+		return fmt.Errorf("Can't write track info: %w", err)
+	}
+
+	return nil
+}
+
+// close closes all files
+func (rm *recorderMount) close() {
+	rm.mu.Lock()
+	rm.closeFiles()
+	rm.mu.Unlock()
+}
+
+// closeFiles closes recording and sidecar files
+func (rm *recorderMount) closeFiles() {
+	if rm.file != nil {
+		rm.file.Close()
+		rm.tracks.Close()
+		rm.file, rm.tracks = nil, nil
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getRecordingName returns name of recording file without extension
+func getRecordingName(mount string, t time.Time) string {
+	name := strings.TrimSuffix(strings.Trim(mount, "/"), path.Ext(mount))
+	name = strings.ReplaceAll(name, "/", "_")
+
+	if name == "" {
+		name = "stream"
+	}
+
+	return name + "_" + t.Format(RECORDING_TIME_LAYOUT)
+}
+
+// getRecordingExt returns extension of recording file
+func getRecordingExt(mount, contentType string) string {
+	ext := strings.ToLower(path.Ext(mount))
+
+	if ext != "" {
+		return ext
+	}
+
+	switch normalizeContentType(contentType) {
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/aac":
+		return ".aac"
+	case "application/ogg":
+		return ".ogg"
+	case "audio/flac":
+		return ".flac"
+	case "audio/webm":
+		return ".webm"
+	}
+
+	return ".bin"
+}
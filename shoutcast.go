@@ -0,0 +1,179 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/req"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	// ErrInvalidPassword is returned if server rejected password
+	ErrInvalidPassword = errors.New("Invalid password")
+
+	// ErrEmptyCompatMeta is returned if metadata has no song, artist or title
+	ErrEmptyCompatMeta = errors.New("Metadata has no song, artist or title")
+)
+
+// compatErrorRegex is regexp for detecting errors in SHOUTcast responses
+var compatErrorRegex = regexp.MustCompile(`(?i)(invalid password|unauthori[sz]ed|access denied|bad password)`)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// UpdateMetaCompat updates meta using SHOUTcast-compatible /admin.cgi endpoint.
+// Password is sent in query, mount credentials are used if set. Mount can be
+// empty for servers with single stream.
+//
+// SHOUTcast checks password against admin password, so API credentials work
+// without mount credentials. Icecast treats password as ICY source password
+// and ignores given mount, updating shoutcast-mount of listen socket instead.
+// Use mount credentials with source password when working with Icecast.
+func (api *API) UpdateMetaCompat(mount string, meta TrackMeta) error {
+	query := meta.toCompatQuery()
+	query["mode"] = "updinfo"
+
+	if mount != "" {
+		var err error
+
+		mount, err = validateMount(mount)
+
+		if err != nil {
+			return err
+		}
+
+		query["mount"] = mount
+	}
+
+	if query["song"] == nil {
+		return ErrEmptyCompatMeta
+	}
+
+	return api.doCompatRequest("/admin.cgi", query)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// toCompatQuery converts metadata to SHOUTcast query. SHOUTcast supports only
+// song title, so artist and title are combined if song is not set. Song is
+// not added to query if metadata is empty.
+func (m TrackMeta) toCompatQuery() req.Query {
+	query := req.Query{}
+
+	switch {
+	case m.Song != "":
+		query["song"] = m.Song
+	case m.Artist != "" && m.Title != "":
+		query["song"] = m.Artist + " - " + m.Title
+	case m.Title != "":
+		query["song"] = m.Title
+	case m.Artist != "":
+		query["song"] = m.Artist
+	}
+
+	query.SetIf(m.URL != "", "url", m.URL)
+	query.SetIf(m.Charset != "", "charset", m.Charset)
+
+	return query
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// doCompatRequest sends request to SHOUTcast-compatible endpoint with password
+// in query
+func (api *API) doCompatRequest(endpoint string, query req.Query) error {
+	return api.intercept(endpoint, query, func(call *APICall) error {
+		creds, err := api.getCredentials(call)
+
+		if err != nil {
+			return err
+		}
+
+		query := maps.Clone(call.Query)
+		query["pass"] = creds.Password
+
+		resp, err := api.engine.Get(req.Request{
+			URL:   api.url + call.Endpoint,
+			Query: query,
+		})
+
+		if err != nil {
+			return fmt.Errorf("Can't send request to server: %w", err)
+		}
+
+		defer resp.Body.Close()
+
+		call.StatusCode = resp.StatusCode
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+		return parseCompatResponse(resp.StatusCode, data)
+	})
+}
+
+// parseCompatResponse parses response from SHOUTcast-compatible endpoint
+func parseCompatResponse(statusCode int, data []byte) error {
+	switch statusCode {
+	case 200:
+		// ok
+	case 401, 403:
+		return ErrInvalidPassword
+	default:
+		return fmt.Errorf("API returned non-ok status code %d", statusCode)
+	}
+
+	data = bytes.TrimSpace(data)
+
+	// Icecast returns default XML response
+	if bytes.Contains(data, []byte("<iceresponse>")) {
+		resp := &iceResponse{}
+		err := xml.Unmarshal(data, resp)
+
+		if err != nil {
+			return fmt.Errorf("Can't parse API response: %w", err)
+		}
+
+		return parseResponse(resp)
+	}
+
+	// SHOUTcast returns HTML page or plain text
+	if compatErrorRegex.Match(data) {
+		return fmt.Errorf("%w (server response: %q)", ErrInvalidPassword, stripTags(string(data)))
+	}
+
+	return nil
+}
+
+// stripTags removes HTML tags from given string
+func stripTags(data string) string {
+	var buf strings.Builder
+
+	isTag := false
+
+	for _, r := range data {
+		switch {
+		case r == '<':
+			isTag = true
+		case r == '>':
+			isTag = false
+			buf.WriteRune(' ')
+		case !isTag:
+			buf.WriteRune(r)
+		}
+	}
+
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
@@ -0,0 +1,148 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+
+	"github.com/essentialkaos/ek/v13/req"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IterClients returns iterator over listeners of given mount. Response is
+// decoded on the fly, so listeners can be filtered and counted without loading
+// whole list into memory.
+func (api *API) IterClients(mount string) iter.Seq2[*Listener, error] {
+	return func(yield func(*Listener, error) bool) {
+		mount, err := validateMount(mount)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		api.streamElements(
+			"/listclients", req.Query{"mount": mount}, "listener",
+			func(dec *xml.Decoder, se *xml.StartElement) bool {
+				listener := &Listener{}
+				err := dec.DecodeElement(listener, se)
+
+				if err != nil {
+					yield(nil, fmt.Errorf("Can't parse API response: %w", err))
+					return false
+				}
+
+				return yield(listener, nil)
+			},
+			func(err error) { yield(nil, err) },
+		)
+	}
+}
+
+// IterSources returns iterator over sources from stats. Response is decoded
+// on the fly, so only one source is kept in memory at a time. Conversion
+// warnings (e.g. dates in unsupported format) are dropped, use GetStats if
+// you need them.
+func (api *API) IterSources() iter.Seq2[*Source, error] {
+	return func(yield func(*Source, error) bool) {
+		api.streamElements(
+			"/stats", nil, "source",
+			func(dec *xml.Decoder, se *xml.StartElement) bool {
+				source := &iceSource{}
+				err := dec.DecodeElement(source, se)
+
+				if err != nil {
+					yield(nil, fmt.Errorf("Can't parse API response: %w", err))
+					return false
+				}
+
+				result, _ := convertSource(source)
+
+				return yield(result, nil)
+			},
+			func(err error) { yield(nil, err) },
+		)
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// streamElements sends request to API and calls handler for every element with
+// given name. Processing stops if handler returns false.
+func (api *API) streamElements(
+	endpoint string, query req.Query, name string,
+	handler func(dec *xml.Decoder, se *xml.StartElement) bool,
+	onError func(err error),
+) {
+	var resp *req.Response
+
+	err := api.intercept(endpoint, query, func(call *APICall) error {
+		var err error
+		resp, err = api.sendRequest(call)
+		return err
+	})
+
+	switch {
+	case err != nil:
+		onError(err)
+		return
+	case resp == nil:
+		onError(ErrRequestSkipped)
+		return
+	}
+
+	defer resp.Body.Close()
+
+	dec := xml.NewDecoder(resp.Body)
+	isRoot := true
+
+	for {
+		token, err := dec.Token()
+
+		if err == io.EOF {
+			return
+		}
+
+		if err != nil {
+			onError(fmt.Errorf("Can't parse API response: %w", err))
+			return
+		}
+
+		se, ok := token.(xml.StartElement)
+
+		if !ok {
+			continue
+		}
+
+		switch {
+		case isRoot && strings.EqualFold(se.Name.Local, "iceresponse"):
+			resp := &iceResponse{}
+			err = dec.DecodeElement(resp, &se)
+
+			if err != nil {
+				onError(fmt.Errorf("Can't parse API response: %w", err))
+			} else if err = parseResponse(resp); err != nil {
+				onError(err)
+			}
+
+			return
+
+		case se.Name.Local == name:
+			if !handler(dec, &se) {
+				return
+			}
+		}
+
+		isRoot = false
+	}
+}
@@ -0,0 +1,299 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// TakeoverStation contains configuration of live takeover for station
+type TakeoverStation struct {
+	// Auto is mount of automatic (autoDJ) source
+	Auto string
+
+	// Live is mount used by presenters
+	Live string
+
+	// LiveMeta is optional metadata set on auto mount while live show is on air.
+	// Original metadata of auto mount is restored after the show.
+	LiveMeta *TrackMeta
+
+	// NoFallback disables setting auto mount as fallback for live mount. Without
+	// fallback Icecast disconnects listeners when presenter disconnects.
+	NoFallback bool
+
+	// MoveNewListeners enables moving listeners which connect to auto mount
+	// while live show is on air
+	MoveNewListeners bool
+}
+
+// TakeoverController switches listeners between auto and live mounts
+type TakeoverController struct {
+	// OnChange is optional callback executed when live show starts or ends.
+	// Callback is called without holding controller lock, but it must not
+	// call Process or Check.
+	OnChange func(station TakeoverStation, live bool)
+
+	// OnError is optional callback for errors occurred while running checks
+	// with Run
+	OnError func(err error)
+
+	api       *API
+	stations  []*takeoverState
+	mu        sync.Mutex
+	processMu sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// takeoverState contains current state of station
+type takeoverState struct {
+	TakeoverStation
+
+	live      bool
+	savedMeta *TrackMeta
+}
+
+// takeoverChange contains info about start or end of live show
+type takeoverChange struct {
+	station TakeoverStation
+	live    bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var ErrNoStations = errors.New("No stations defined")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewTakeoverController creates new live takeover controller
+func NewTakeoverController(api *API, stations ...TakeoverStation) (*TakeoverController, error) {
+	if api == nil {
+		return nil, ErrNilAPI
+	}
+
+	if len(stations) == 0 {
+		return nil, ErrNoStations
+	}
+
+	c := &TakeoverController{api: api}
+
+	for _, s := range stations {
+		if s.Auto == "" || s.Live == "" {
+			return nil, fmt.Errorf("Station %q → %q has empty mount", s.Auto, s.Live)
+		}
+
+		s.Auto, s.Live = normalizeMount(s.Auto), normalizeMount(s.Live)
+		c.stations = append(c.stations, &takeoverState{TakeoverStation: s})
+	}
+
+	return c, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Run runs checks with given interval until context is canceled. Check errors
+// are passed to OnError callback.
+func (c *TakeoverController) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Check()
+
+		if err != nil && c.OnError != nil {
+			c.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches stats and updates state of all stations
+func (c *TakeoverController) Check() error {
+	stats, err := c.api.GetStats()
+
+	if err != nil {
+		return err
+	}
+
+	return c.Process(stats)
+}
+
+// Process updates state of all stations using given stats snapshot
+func (c *TakeoverController) Process(stats *Stats) error {
+	if stats == nil {
+		return nil
+	}
+
+	c.processMu.Lock()
+	defer c.processMu.Unlock()
+
+	var errs []error
+	var changes []*takeoverChange
+
+	for _, s := range c.stations {
+		change, err := c.processStation(s, stats)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Live, err))
+		}
+
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	if c.OnChange != nil {
+		for _, ch := range changes {
+			c.OnChange(ch.station, ch.live)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsLive returns true if live show is on air on given live mount
+func (c *TakeoverController) IsLive(live string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range c.stations {
+		if s.Live == normalizeMount(live) {
+			return s.live
+		}
+	}
+
+	return false
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processStation updates state of given station and returns info about start
+// or end of live show
+func (c *TakeoverController) processStation(s *takeoverState, stats *Stats) (*takeoverChange, error) {
+	live := stats.Sources[s.Live]
+	auto := stats.Sources[s.Auto]
+
+	c.mu.Lock()
+	isLive := s.live
+	c.mu.Unlock()
+
+	switch {
+	case !isLive && live != nil:
+		err := c.startLive(s, auto)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return c.setLive(s, true), nil
+
+	case isLive && live == nil:
+		return c.setLive(s, false), c.stopLive(s)
+
+	case isLive && s.MoveNewListeners && auto != nil &&
+		auto.Stats != nil && auto.Stats.Listeners > 0:
+		return nil, c.api.MoveClients(s.Auto, s.Live)
+	}
+
+	return nil, nil
+}
+
+// startLive moves listeners from auto mount to live mount
+func (c *TakeoverController) startLive(s *takeoverState, auto *Source) error {
+	if !s.NoFallback {
+		err := c.api.UpdateFallback(s.Live, s.Auto)
+
+		if err != nil {
+			return fmt.Errorf("Can't set fallback: %w", err)
+		}
+	}
+
+	if auto == nil {
+		return nil
+	}
+
+	err := c.api.MoveClients(s.Auto, s.Live)
+
+	if err != nil {
+		return fmt.Errorf("Can't move listeners: %w", err)
+	}
+
+	if s.LiveMeta == nil {
+		return nil
+	}
+
+	s.savedMeta = getTrackMeta(auto)
+
+	err = c.api.UpdateMeta(s.Auto, *s.LiveMeta)
+
+	if err != nil {
+		return fmt.Errorf("Can't update metadata: %w", err)
+	}
+
+	return nil
+}
+
+// stopLive restores metadata of auto mount after live show. Listeners are
+// returned to auto mount by Icecast using fallback set on show start.
+func (c *TakeoverController) stopLive(s *takeoverState) error {
+	if s.savedMeta == nil {
+		return nil
+	}
+
+	meta := *s.savedMeta
+	s.savedMeta = nil
+
+	err := c.api.UpdateMeta(s.Auto, meta)
+
+	if err != nil {
+		return fmt.Errorf("Can't restore metadata: %w", err)
+	}
+
+	return nil
+}
+
+// setLive updates live state of given station
+func (c *TakeoverController) setLive(s *takeoverState, live bool) *takeoverChange {
+	c.mu.Lock()
+	s.live = live
+	c.mu.Unlock()
+
+	return &takeoverChange{station: s.TakeoverStation, live: live}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getTrackMeta returns metadata of current track of given source
+func getTrackMeta(source *Source) *TrackMeta {
+	if source == nil || source.Track == nil {
+		return nil
+	}
+
+	if source.Track.RawInfo == "" && source.Track.Title == "" {
+		return nil
+	}
+
+	return &TrackMeta{
+		Song:    source.Track.RawInfo,
+		Artist:  source.Track.Artist,
+		Title:   source.Track.Title,
+		Artwork: source.Track.Artwork,
+	}
+}
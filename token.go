@@ -0,0 +1,261 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	TOKEN_PARAM_EXPIRES   = "exp"
+	TOKEN_PARAM_LISTENER  = "lid"
+	TOKEN_PARAM_KEY       = "kid"
+	TOKEN_PARAM_SIGNATURE = "sig"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// TokenSigner signs and validates expiring listen URLs
+type TokenSigner struct {
+	// Skew is max allowed clock difference between signer and validator
+	Skew time.Duration
+
+	keys    map[string][]byte
+	current string
+	mu      sync.RWMutex
+}
+
+// Token contains info from validated listen URL
+type Token struct {
+	KeyID      string
+	ListenerID string
+	Mount      string
+	Expires    time.Time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrTokenMissing    = errors.New("URL has no token")
+	ErrTokenMalformed  = errors.New("Token is malformed")
+	ErrTokenExpired    = errors.New("Token is expired")
+	ErrTokenUnknownKey = errors.New("Token is signed with unknown key")
+	ErrTokenSignature  = errors.New("Token signature is invalid")
+	ErrEmptyKey        = errors.New("Key is empty")
+	ErrEmptyKeyID      = errors.New("Key ID is empty")
+	ErrTokenAction     = errors.New("Action is not supported by token authentication")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewTokenSigner creates new signer with given signing key
+func NewTokenSigner(keyID string, key []byte) (*TokenSigner, error) {
+	s := &TokenSigner{keys: make(map[string][]byte)}
+	err := s.AddKey(keyID, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.current = keyID
+
+	return s, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AddKey adds new key which can be used for validation. Use SetCurrentKey to
+// start signing new URLs with this key.
+func (s *TokenSigner) AddKey(keyID string, key []byte) error {
+	switch {
+	case keyID == "":
+		return ErrEmptyKeyID
+	case len(key) == 0:
+		return ErrEmptyKey
+	}
+
+	s.mu.Lock()
+	s.keys[keyID] = key
+	s.mu.Unlock()
+
+	return nil
+}
+
+// SetCurrentKey sets key used for signing new URLs
+func (s *TokenSigner) SetCurrentKey(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keys[keyID] == nil {
+		return fmt.Errorf("Unknown key %q", keyID)
+	}
+
+	s.current = keyID
+
+	return nil
+}
+
+// RemoveKey removes key, so URLs signed with it will be rejected. Current key
+// can't be removed.
+func (s *TokenSigner) RemoveKey(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keyID == s.current {
+		return fmt.Errorf("Can't remove current key %q", keyID)
+	}
+
+	delete(s.keys, keyID)
+
+	return nil
+}
+
+// Sign adds token with expiration date and listener ID to given listen URL.
+// Token is valid only for mount from the URL.
+func (s *TokenSigner) Sign(listenURL, listenerID string, expires time.Time) (string, error) {
+	u, err := url.Parse(listenURL)
+
+	if err != nil {
+		return "", fmt.Errorf("Can't parse listen URL: %w", err)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		return "", fmt.Errorf("Listen URL has no mount")
+	}
+
+	s.mu.RLock()
+	keyID, key := s.current, s.keys[s.current]
+	s.mu.RUnlock()
+
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	query := u.Query()
+
+	query.Set(TOKEN_PARAM_EXPIRES, exp)
+	query.Set(TOKEN_PARAM_KEY, keyID)
+	query.Set(TOKEN_PARAM_SIGNATURE, signToken(key, u.Path, listenerID, exp, keyID))
+
+	if listenerID != "" {
+		query.Set(TOKEN_PARAM_LISTENER, listenerID)
+	} else {
+		query.Del(TOKEN_PARAM_LISTENER)
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// SignSource signs listen URL of given source
+func (s *TokenSigner) SignSource(source *Source, listenerID string, ttl time.Duration) (string, error) {
+	if source == nil {
+		return "", fmt.Errorf("Source is nil")
+	}
+
+	return s.Sign(source.ListenURL, listenerID, time.Now().Add(ttl))
+}
+
+// Validate validates token from query of request to given mount
+func (s *TokenSigner) Validate(mount string, query url.Values) (*Token, error) {
+	exp := query.Get(TOKEN_PARAM_EXPIRES)
+	keyID := query.Get(TOKEN_PARAM_KEY)
+	sig := query.Get(TOKEN_PARAM_SIGNATURE)
+	listenerID := query.Get(TOKEN_PARAM_LISTENER)
+
+	if sig == "" {
+		return nil, ErrTokenMissing
+	}
+
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+
+	if err != nil || keyID == "" {
+		return nil, ErrTokenMalformed
+	}
+
+	s.mu.RLock()
+	key := s.keys[keyID]
+	s.mu.RUnlock()
+
+	if key == nil {
+		return nil, ErrTokenUnknownKey
+	}
+
+	mount = normalizeMount(mount)
+	expected := signToken(key, mount, listenerID, exp, keyID)
+
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return nil, ErrTokenSignature
+	}
+
+	token := &Token{
+		KeyID:      keyID,
+		ListenerID: listenerID,
+		Mount:      mount,
+		Expires:    time.Unix(expUnix, 0),
+	}
+
+	if time.Now().After(token.Expires.Add(s.Skew)) {
+		return nil, ErrTokenExpired
+	}
+
+	return token, nil
+}
+
+// Authenticate validates listener tokens in URL authentication callbacks. Listening
+// time is limited by token expiration date. Listener remove and mount callbacks
+// are allowed, source authentication (stream_auth) and unknown actions are
+// rejected.
+func (s *TokenSigner) Authenticate(ctx context.Context, r *AuthRequest) (AuthResult, error) {
+	switch r.Action {
+	case AUTH_LISTENER_ADD:
+		return s.authenticateListener(r), nil
+	case AUTH_LISTENER_REMOVE, AUTH_MOUNT_ADD, AUTH_MOUNT_REMOVE:
+		return AuthResult{Allow: true}, nil
+	}
+
+	return AuthResult{Message: ErrTokenAction.Error()}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// authenticateListener validates token of listener and limits listening time
+// by token expiration date
+func (s *TokenSigner) authenticateListener(r *AuthRequest) AuthResult {
+	token, err := s.Validate(r.Mount, r.Query)
+
+	if err != nil {
+		return AuthResult{Message: err.Error()}
+	}
+
+	timeLimit := time.Until(token.Expires.Add(s.Skew)).Truncate(time.Second)
+
+	// Zero time limit means unlimited listening, so token which is about to
+	// expire must be rejected
+	if timeLimit < time.Second {
+		return AuthResult{Message: ErrTokenExpired.Error()}
+	}
+
+	return AuthResult{Allow: true, TimeLimit: timeLimit}
+}
+
+// signToken generates token signature
+func signToken(key []byte, mount, listenerID, exp, keyID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(mount + "\n" + listenerID + "\n" + exp + "\n" + keyID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,347 @@
+package icecast
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2025 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/essentialkaos/ek/v13/req"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	YP_ACTION_ADD    = "add"
+	YP_ACTION_TOUCH  = "touch"
+	YP_ACTION_REMOVE = "remove"
+)
+
+// YP_DEFAULT_TOUCH_FREQ is default touch interval used if YP server doesn't
+// provide it
+const YP_DEFAULT_TOUCH_FREQ = 5 * time.Minute
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// YPClient is client for Xiph YP directory protocol
+type YPClient struct {
+	engine   *req.Engine
+	url      string
+	password string
+	entries  map[string]*YPEntry
+	mu       sync.Mutex
+}
+
+// YPEntry contains info about stream listed on YP directory
+type YPEntry struct {
+	Mount     string
+	SID       string
+	Song      string
+	TouchFreq time.Duration
+	LastTouch time.Time
+}
+
+// ypResponse contains YP server response data
+type ypResponse struct {
+	SID       string
+	TouchFreq time.Duration
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	ErrYPNoSource    = errors.New("Source is nil")
+	ErrYPNotListed   = errors.New("Mount is not listed on YP directory")
+	ErrYPNoListenURL = errors.New("Source has no listen URL")
+	ErrYPRejected    = errors.New("YP server rejected")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewYPClient creates new YP directory client
+func NewYPClient(url string) (*YPClient, error) {
+	if url == "" {
+		return nil, ErrEmptyURL
+	}
+
+	engine := &req.Engine{}
+	engine.SetUserAgent("go-icecast", "3")
+
+	return &YPClient{
+		engine:  engine,
+		url:     url,
+		entries: make(map[string]*YPEntry),
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SetUserAgent set user-agent string based on app name and version
+func (c *YPClient) SetUserAgent(app, version string) {
+	c.engine.SetUserAgent(app, version, USER_AGENT)
+}
+
+// SetPassword sets password used for adding streams (cpswd)
+func (c *YPClient) SetPassword(password string) {
+	c.password = password
+}
+
+// Entry returns copy of YP entry for given mount
+func (c *YPClient) Entry(mount string) *YPEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entries[normalizeMount(mount)]
+
+	if entry == nil {
+		return nil
+	}
+
+	entryCopy := *entry
+
+	return &entryCopy
+}
+
+// Add lists source with given mount on YP directory
+func (c *YPClient) Add(mount string, source *Source) error {
+	if source == nil {
+		return ErrYPNoSource
+	}
+
+	if source.ListenURL == "" {
+		return ErrYPNoListenURL
+	}
+
+	query := req.Query{
+		"action":    YP_ACTION_ADD,
+		"listenurl": source.ListenURL,
+		"genre":     source.Genre,
+		"b":         source.Bitrate.String(),
+	}
+
+	query.SetIf(c.password != "", "cpswd", c.password)
+
+	if source.Info != nil {
+		query["sn"] = source.Info.Name
+		query["desc"] = source.Info.Description
+		query["url"] = source.Info.URL
+		query["type"] = source.Info.Type
+		query["stype"] = source.Info.SubType
+	}
+
+	if source.AudioInfo != nil && source.AudioInfo.RawInfo != "" {
+		query["audio_info"] = source.AudioInfo.RawInfo
+	}
+
+	resp, err := c.doRequest(query)
+
+	if err != nil {
+		return err
+	}
+
+	entry := &YPEntry{
+		Mount:     normalizeMount(mount),
+		SID:       resp.SID,
+		TouchFreq: resp.TouchFreq,
+		LastTouch: time.Now(),
+	}
+
+	if entry.SID == "" {
+		return fmt.Errorf("YP server didn't return SID for mount %s", entry.Mount)
+	}
+
+	c.mu.Lock()
+	c.entries[entry.Mount] = entry
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Touch updates info (current song and listeners) about listed source
+func (c *YPClient) Touch(mount string, source *Source) error {
+	if source == nil {
+		return ErrYPNoSource
+	}
+
+	entry := c.Entry(mount)
+
+	if entry == nil {
+		return ErrYPNotListed
+	}
+
+	query := req.Query{"action": YP_ACTION_TOUCH, "sid": entry.SID}
+
+	if source.Track != nil {
+		query["st"] = source.Track.RawInfo
+	}
+
+	if source.Stats != nil {
+		query["listeners"] = source.Stats.Listeners
+		query["max_listeners"] = source.Stats.MaxListeners
+	}
+
+	if source.Info != nil {
+		query["stype"] = source.Info.SubType
+	}
+
+	_, err := c.doRequest(query)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		// SID is not valid anymore, so stream must be added again. Transient
+		// errors (timeouts, 5xx) keep SID to avoid duplicate listings.
+		if errors.Is(err, ErrYPRejected) {
+			delete(c.entries, entry.Mount)
+		}
+
+		return err
+	}
+
+	if c.entries[entry.Mount] != nil {
+		c.entries[entry.Mount].LastTouch = time.Now()
+		c.entries[entry.Mount].Song, _ = query["st"].(string)
+	}
+
+	return nil
+}
+
+// Remove removes source with given mount from YP directory
+func (c *YPClient) Remove(mount string) error {
+	entry := c.Entry(mount)
+
+	if entry == nil {
+		return ErrYPNotListed
+	}
+
+	c.mu.Lock()
+	delete(c.entries, entry.Mount)
+	c.mu.Unlock()
+
+	_, err := c.doRequest(req.Query{"action": YP_ACTION_REMOVE, "sid": entry.SID})
+
+	return err
+}
+
+// Sync synchronizes YP directory with given stats. Public sources which are not
+// listed are added, listed sources are touched if touch interval has passed or
+// current song has changed and sources which are gone or not public anymore
+// are removed.
+func (c *YPClient) Sync(stats *Stats) error {
+	if stats == nil {
+		return fmt.Errorf("Stats is nil")
+	}
+
+	var errs []error
+
+	for mount, source := range stats.Sources {
+		var err error
+
+		entry := c.Entry(mount)
+
+		switch {
+		case entry == nil && source.Public:
+			err = c.Add(mount, source)
+		case entry != nil && !source.Public:
+			err = c.Remove(mount)
+		case entry != nil && c.isTouchRequired(entry, source):
+			err = c.Touch(mount, source)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", mount, err))
+		}
+	}
+
+	for _, mount := range c.listedMounts() {
+		if stats.GetSource(mount) != nil {
+			continue
+		}
+
+		err := c.Remove(mount)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", mount, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// doRequest sends request to YP server
+func (c *YPClient) doRequest(query req.Query) (*ypResponse, error) {
+	resp, err := c.engine.Post(req.Request{
+		URL:         c.url,
+		Body:        query.Encode(),
+		ContentType: "application/x-www-form-urlencoded",
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't send request to YP server: %w", err)
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("YP server returned non-ok status code %d", resp.StatusCode)
+	}
+
+	if resp.Header.Get("YPResponse") != "1" {
+		msg := resp.Header.Get("YPMessage")
+
+		if msg == "" {
+			msg = "unknown error"
+		}
+
+		return nil, fmt.Errorf("%w %q action: %s", ErrYPRejected, query["action"], msg)
+	}
+
+	result := &ypResponse{
+		SID:       resp.Header.Get("SID"),
+		TouchFreq: YP_DEFAULT_TOUCH_FREQ,
+	}
+
+	freq, err := strconv.Atoi(resp.Header.Get("TouchFreq"))
+
+	if err == nil && freq > 0 {
+		result.TouchFreq = time.Duration(freq) * time.Second
+	}
+
+	return result, nil
+}
+
+// isTouchRequired returns true if entry must be touched
+func (c *YPClient) isTouchRequired(entry *YPEntry, source *Source) bool {
+	if source.Track != nil && source.Track.RawInfo != entry.Song {
+		return true
+	}
+
+	return time.Since(entry.LastTouch) >= entry.TouchFreq
+}
+
+// listedMounts returns slice with all listed mounts
+func (c *YPClient) listedMounts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result []string
+
+	for mount := range c.entries {
+		result = append(result, mount)
+	}
+
+	return result
+}